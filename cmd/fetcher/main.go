@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"server/internal/fetcher"
+	"server/internal/market"
+	"server/internal/storage"
+	"server/internal/symbols"
+	"strconv"
 	"time"
 
 	goredis "github.com/redis/go-redis/v9"
@@ -55,6 +60,63 @@ func initRedis(logger *slog.Logger) *goredis.Client {
 	return client
 }
 
+// initArchiver sets up raw response archiving from ARCHIVE_TARGET: "disk"
+// writes under ARCHIVE_DIR, "bucket" uploads with the same BUCKET_*
+// credentials the processor uses for daily CSVs. Unset disables it.
+func initArchiver(loc *time.Location, logger *slog.Logger) (*fetcher.Archiver, error) {
+	switch target := os.Getenv("ARCHIVE_TARGET"); target {
+	case "":
+		logger.Info("Raw response archiving disabled")
+		return nil, nil
+
+	case "disk":
+		dir := os.Getenv("ARCHIVE_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("ARCHIVE_DIR must be set for disk archiving")
+		}
+		logger.Info("Archiving raw responses to disk", slog.String("dir", dir))
+		return fetcher.NewArchiver(fetcher.DiskStore{Dir: dir}, loc, logger), nil
+
+	case "bucket":
+		endpoint := os.Getenv("BUCKET_ENDPOINT")
+		region := os.Getenv("BUCKET_REGION")
+		bucket := os.Getenv("BUCKET_NAME")
+		accessKeyID := os.Getenv("BUCKET_ACCESS_KEY_ID")
+		secretAccessKey := os.Getenv("BUCKET_SECRET_ACCESS_KEY")
+
+		if endpoint == "" || region == "" || bucket == "" || accessKeyID == "" || secretAccessKey == "" {
+			return nil, fmt.Errorf("bucket credentials not fully set")
+		}
+		logger.Info("Archiving raw responses to bucket", slog.String("bucket", bucket))
+		uploader := storage.NewBucketUploader(endpoint, region, bucket, accessKeyID, secretAccessKey)
+		return fetcher.NewArchiver(uploader, loc, logger), nil
+
+	default:
+		return nil, fmt.Errorf("unknown ARCHIVE_TARGET %q, want disk or bucket", target)
+	}
+}
+
+// replayOptionsFromEnv reads REPLAY_DIR (required), REPLAY_SPEED (default
+// 1, real time) and REPLAY_REBASE (default true).
+func replayOptionsFromEnv() (fetcher.ReplayOptions, error) {
+	opts := fetcher.ReplayOptions{
+		Dir:    os.Getenv("REPLAY_DIR"),
+		Speed:  1,
+		Rebase: os.Getenv("REPLAY_REBASE") != "false",
+	}
+	if opts.Dir == "" {
+		return opts, fmt.Errorf("REPLAY_DIR must be set")
+	}
+	if v := os.Getenv("REPLAY_SPEED"); v != "" {
+		speed, err := strconv.ParseFloat(v, 64)
+		if err != nil || speed < 0 {
+			return opts, fmt.Errorf("invalid REPLAY_SPEED %q", v)
+		}
+		opts.Speed = speed
+	}
+	return opts, nil
+}
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
@@ -68,14 +130,87 @@ func main() {
 		}
 	}()
 
-	writer := fetcher.NewStreamWriter(redisClient, "nifty50:option_chain")
+	syms, err := symbols.FromEnv()
+	if err != nil {
+		logger.Error("Invalid NSE_SYMBOLS", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	writers := make(map[string]fetcher.Writer, len(syms))
+	for _, sym := range syms {
+		writers[sym.Name] = fetcher.NewStreamWriter(redisClient, sym.StreamKey())
+		logger.Info("Fetching symbol", slog.String("symbol", sym.Name), slog.String("stream", sym.StreamKey()))
+	}
 
-	browser := fetcher.NewBrowser()
-	defer browser.Close()
+	expiries, err := fetcher.ExpiryRuleFromEnv()
+	if err != nil {
+		logger.Error("Invalid NSE_EXPIRIES", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	logger.Info("Expiry selection", slog.String("rule", expiries.String()))
+
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		logger.Error("Failed to load location", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	calendar, err := market.CalendarFromEnv(loc, logger)
+	if err != nil {
+		logger.Error("Failed to load market calendar", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	schedule, err := market.ScheduleFromEnv(calendar)
+	if err != nil {
+		logger.Error("Invalid market timings", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	archiver, err := initArchiver(loc, logger)
+	if err != nil {
+		logger.Error("Invalid archive settings", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
 
 	fetcherService := &fetcher.FetcherService{
-		Writer:  writer,
-		Browser: browser,
+		Symbols:  syms,
+		Writers:  writers,
+		Expiries: expiries,
+		Schedule: schedule,
+	}
+
+	// NSE_SOURCE picks the backend: headless Chrome by default, a plain
+	// cookie-jar HTTP client that needs no Chromium in the image, or
+	// replay of recorded captures for offline runs.
+	switch source := os.Getenv("NSE_SOURCE"); source {
+	case "replay":
+		opts, err := replayOptionsFromEnv()
+		if err != nil {
+			logger.Error("Invalid replay settings", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+		logger.Info("Replaying captures", slog.String("dir", opts.Dir))
+		if err := fetcherService.ReplayData(ctx, logger, opts); err != nil {
+			logger.Error("Replay failed", slog.String("err", err.Error()))
+		}
+		<-ctx.Done()
+		return
+	case "", "browser":
+		logger.Info("Using headless Chrome source")
+		browser := fetcher.NewBrowser()
+		defer browser.Close()
+		browser.Archiver = archiver
+		fetcherService.Source = browser
+	case "http":
+		logger.Info("Using plain HTTP source")
+		client := fetcher.NewHTTPClient()
+		defer client.Close()
+		client.Archiver = archiver
+		fetcherService.Source = client
+	default:
+		logger.Error("Invalid NSE_SOURCE, want browser, http or replay", slog.String("source", source))
+		os.Exit(1)
 	}
 
 	if err := fetcherService.FetchData(ctx, logger); err != nil {
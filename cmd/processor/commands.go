@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"server/internal/db"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runCommand runs a one-off maintenance command instead of the processor
+// and returns the process exit code.
+func runCommand(ctx context.Context, logger *slog.Logger, name string, args []string) int {
+	switch name {
+	case "dedup":
+		return runDedup(ctx, logger, args)
+	case "migrate":
+		return runMigrate(ctx, logger, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", name, usage)
+		return 2
+	}
+}
+
+const usage = `usage:
+  processing                           run the processor
+  processing dedup [-dry-run]          remove duplicate rows, then migrate up
+  processing migrate up                apply pending migrations
+  processing migrate down [-steps N]   revert the last N migrations (default 1)
+  processing migrate status            list migrations and when they ran
+`
+
+// runMigrate applies, reverts or lists schema migrations.
+func runMigrate(ctx context.Context, logger *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	pool, err := pgxpool.New(ctx, databaseURL())
+	if err != nil {
+		logger.Error("Failed to connect to DB", slog.String("error", err.Error()))
+		return 1
+	}
+	defer pool.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx, pool)
+		for _, m := range applied {
+			logger.Info("Applied migration", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			logger.Error("Migration failed", slog.String("error", err.Error()))
+			return 1
+		}
+		if len(applied) == 0 {
+			logger.Info("Schema already up to date")
+		}
+
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+			return 2
+		}
+		reverted, err := db.MigrateDown(ctx, pool, *steps)
+		for _, m := range reverted {
+			logger.Info("Reverted migration", slog.Int("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			logger.Error("Revert failed", slog.String("error", err.Error()))
+			return 1
+		}
+
+	case "status":
+		states, err := db.MigrationStatus(ctx, pool)
+		if err != nil {
+			logger.Error("Failed to read migration status", slog.String("error", err.Error()))
+			return 1
+		}
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate action %q\n%s", args[0], usage)
+		return 2
+	}
+	return 0
+}
+
+// runDedup removes duplicate option chain rows left by writes made before
+// the unique keys existed, then applies the migration that builds them.
+func runDedup(ctx context.Context, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("dedup", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "count duplicate rows without deleting them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Connect without NewDB: its migrations are what duplicates break.
+	pool, err := pgxpool.New(ctx, databaseURL())
+	if err != nil {
+		logger.Error("Failed to connect to DB", slog.String("error", err.Error()))
+		return 1
+	}
+	defer pool.Close()
+
+	// The keys include columns added by earlier migrations, so apply as
+	// many as the duplicates allow before looking for them.
+	if _, err := db.MigrateUp(ctx, pool); err != nil {
+		logger.Info("Schema not fully migrated yet", slog.String("reason", err.Error()))
+	}
+
+	result, err := db.DedupOptionChain(ctx, pool, *dryRun)
+	if err != nil {
+		logger.Error("Dedup failed", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if *dryRun {
+		logger.Info("Found duplicate rows",
+			slog.Int64("option_chain_snapshots", result.Snapshots),
+			slog.Int64("option_chain_summaries", result.Summaries))
+		return 0
+	}
+
+	logger.Info("Removed duplicate rows",
+		slog.Int64("option_chain_snapshots", result.Snapshots),
+		slog.Int64("option_chain_summaries", result.Summaries))
+
+	if _, err := db.MigrateUp(ctx, pool); err != nil {
+		logger.Error("Failed to build unique keys", slog.String("error", err.Error()))
+		return 1
+	}
+	logger.Info("Unique keys in place")
+	return 0
+}
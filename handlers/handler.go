@@ -6,23 +6,82 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
-	"server/internal/models"
-	"sync"
+	"server/internal/market"
+	"server/internal/processing"
+	"strconv"
+	"strings"
 	"time"
-
 )
 
-func HandlePost(data *[]models.ResponsePayload, loc *time.Location, mu *sync.RWMutex, logger *slog.Logger) http.HandlerFunc {
+// preflight sets the CORS headers allowing methods from any origin and
+// answers the request if it is a CORS preflight, reporting whether it did.
+func preflight(w http.ResponseWriter, r *http.Request, methods string) bool {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", methods)
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Type")
+
+	if r.Method != http.MethodOptions {
+		return false
+	}
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+// daySymbol returns the day of the symbol named by the `symbol` query
+// parameter, or of defaultSymbol when it is omitted. For an unknown symbol
+// it replies 404 and returns false.
+func daySymbol(w http.ResponseWriter, r *http.Request, days map[string]*processing.Day, defaultSymbol string) (*processing.Day, bool) {
+	symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		symbol = defaultSymbol
+	}
+	day, ok := days[symbol]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown symbol %q", symbol), http.StatusNotFound)
+	}
+	return day, ok
+}
+
+// HandlePost streams the current day's rows for one symbol over SSE. The
+// symbol is picked with the `symbol` query parameter, defaulting to
+// defaultSymbol when omitted. The optional `window` query parameter limits
+// the rows to that many listed strikes either side of each expiry's ATM
+// strike, and `buildup` and `interval_buildup` (comma-separated, e.g.
+// long_buildup,short_covering) to rows where either side shows one of
+// those buildups since the previous close or the previous snapshot. Each
+// unnamed message carrying the rows is followed by an "aggregates" event
+// with the day's per-expiry aggregates, max pain included, which clients
+// that only listen for messages ignore.
+func HandlePost(days map[string]*processing.Day, defaultSymbol string, schedule *market.Schedule, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Handle CORS and preflight request
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			// Preflight request (CORS)
-			w.WriteHeader(http.StatusOK)
+		if preflight(w, r, "GET, POST, OPTIONS") {
+			return
+		}
+
+		day, ok := daySymbol(w, r, days, defaultSymbol)
+		if !ok {
+			return
+		}
+
+		window := -1
+		if v := r.URL.Query().Get("window"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("invalid window %q, want a non-negative number of strikes", v), http.StatusBadRequest)
+				return
+			}
+			window = n
+		}
+
+		sinceClose, err := processing.ParseBuildups(r.URL.Query().Get("buildup"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sinceSnapshot, err := processing.ParseBuildups(r.URL.Query().Get("interval_buildup"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -38,27 +97,103 @@ func HandlePost(data *[]models.ResponsePayload, loc *time.Location, mu *sync.RWM
 		}
 
 		for {
-			now := time.Now().In(loc)
-			endTime := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, loc)
-
-			if now.After(endTime) {
+			// Nothing new arrives until the next session: the day is reset
+			// overnight, and on weekends and holidays there is no session.
+			if state := schedule.At(time.Now()); state.Phase == market.Reset || !state.TradingDay {
 				log.Println("SSE stream paused")
 				break
 			}
 
-			mu.RLock()
-			jsonRecords, err := json.Marshal(data)
-			mu.RUnlock()
+			jsonRecords, err := json.Marshal(processing.WithBuildup(
+				processing.WithinATM(day.Records(), window), sinceClose, sinceSnapshot))
 
 			if err != nil {
-				logger.Error("Error marshalling records:", slog.String("error", err.Error())	)
+				logger.Error("Error marshalling records:", slog.String("error", err.Error()))
 				return
 			}
 
 			fmt.Fprintf(w, "data: %s\n\n", jsonRecords)
+
+			jsonAggregates, err := json.Marshal(day.Aggregates())
+			if err != nil {
+				logger.Error("Error marshalling aggregates:", slog.String("error", err.Error()))
+				return
+			}
+			fmt.Fprintf(w, "event: aggregates\ndata: %s\n\n", jsonAggregates)
 			flusher.Flush()
 
 			time.Sleep(3 * time.Minute)
 		}
 	}
 }
+
+// HandleSummary returns the current day's chain-level summaries for one
+// symbol as JSON. The symbol is picked the same way as in HandlePost.
+func HandleSummary(days map[string]*processing.Day, defaultSymbol string, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if preflight(w, r, "GET, OPTIONS") {
+			return
+		}
+
+		day, ok := daySymbol(w, r, days, defaultSymbol)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(day.Summaries()); err != nil {
+			logger.Error("Error encoding summaries", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// maxPainPoint is one snapshot's max pain for one expiry.
+type maxPainPoint struct {
+	Timestamp       time.Time `json:"timestamp"`
+	ExpiryDate      time.Time `json:"expiryDate"`
+	UnderlyingValue float64   `json:"underlyingValue"`
+	MaxPain         *float64  `json:"maxPain"`
+}
+
+// HandleMaxPain returns the current day's max pain history for one symbol
+// as JSON, oldest first. The symbol is picked the same way as in
+// HandlePost; the optional `expiry` query parameter (YYYY-MM-DD) limits it
+// to one expiry.
+func HandleMaxPain(days map[string]*processing.Day, defaultSymbol string, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if preflight(w, r, "GET, OPTIONS") {
+			return
+		}
+
+		day, ok := daySymbol(w, r, days, defaultSymbol)
+		if !ok {
+			return
+		}
+
+		expiry := r.URL.Query().Get("expiry")
+		if expiry != "" {
+			if _, err := time.Parse("2006-01-02", expiry); err != nil {
+				http.Error(w, fmt.Sprintf("invalid expiry %q, want YYYY-MM-DD", expiry), http.StatusBadRequest)
+				return
+			}
+		}
+
+		points := []maxPainPoint{}
+		for _, a := range day.Aggregates() {
+			if expiry != "" && a.ExpiryDate.Format("2006-01-02") != expiry {
+				continue
+			}
+			points = append(points, maxPainPoint{
+				Timestamp:       a.Timestamp,
+				ExpiryDate:      a.ExpiryDate,
+				UnderlyingValue: a.UnderlyingValue,
+				MaxPain:         a.MaxPain,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(points); err != nil {
+			logger.Error("Error encoding max pain", slog.String("error", err.Error()))
+		}
+	}
+}
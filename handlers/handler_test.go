@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/internal/market"
+	"server/internal/processing"
+	"testing"
+	"time"
+)
+
+func TestHandlePostEndsOnHoliday(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// A calendar that makes today a holiday, whatever day the test runs.
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	calendar := fmt.Sprintf(`{"holidays": [{"date": %q, "description": "Test holiday"}]}`,
+		time.Now().In(loc).Format("2006-01-02"))
+	if err := os.WriteFile(path, []byte(calendar), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := market.LoadCalendar(path, loc, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule := market.NewSchedule(cal, market.DefaultTimings)
+
+	days := map[string]*processing.Day{"NIFTY": processing.NewDay()}
+	handler := HandlePost(days, "NIFTY", schedule, logger)
+
+	done := make(chan struct{})
+	rec := httptest.NewRecorder()
+	go func() {
+		handler(rec, httptest.NewRequest("GET", "/api/data", nil))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open on a holiday")
+	}
+	if body := rec.Body.String(); body != "" {
+		t.Errorf("sent %q on a holiday, want nothing", body)
+	}
+}
+
+func TestHandleSummarySymbol(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	days := map[string]*processing.Day{"NIFTY": processing.NewDay(), "RELIANCE": processing.NewDay()}
+	handler := HandleSummary(days, "NIFTY", logger)
+
+	tests := []struct {
+		method, target string
+		want           int
+	}{
+		{"GET", "/api/summary", http.StatusOK},
+		{"GET", "/api/summary?symbol=reliance", http.StatusOK},
+		{"GET", "/api/summary?symbol=BANKNIFTY", http.StatusNotFound},
+		{"OPTIONS", "/api/summary?symbol=BANKNIFTY", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("%s %s: Access-Control-Allow-Origin %q, want *", tt.method, tt.target, got)
+		}
+	}
+}
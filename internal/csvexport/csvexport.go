@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"os"
 	"server/internal/models"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,10 +17,85 @@ var header = []string{
 	"ce_oi", "ce_ch_oi", "ce_ch_oi_pct", "ce_vol", "ce_iv", "ce_ltp",
 	"pe_oi", "pe_ch_oi", "pe_ch_oi_pct", "pe_vol", "pe_iv", "pe_ltp",
 	"intraday_pcr", "pcr",
+	"ce_bid_price", "ce_bid_qty", "ce_ask_price", "ce_ask_qty",
+	"pe_bid_price", "pe_bid_qty", "pe_ask_price", "pe_ask_qty",
+	"ce_delta", "ce_gamma", "ce_theta", "ce_vega", "ce_rho",
+	"pe_delta", "pe_gamma", "pe_theta", "pe_vega", "pe_rho",
+	"ce_iv_computed", "pe_iv_computed",
+	"max_pain",
+	"atm_strike", "strikes_from_atm", "ce_moneyness", "pe_moneyness",
+	"ce_change", "pe_change", "ce_buildup", "pe_buildup", "ce_interval_buildup", "pe_interval_buildup",
 }
 
-// ToCSV renders option chain records as CSV bytes.
-func ToCSV(records []models.ResponsePayload) ([]byte, error) {
+// Precision maps a CSV column to the number of decimals its values are
+// written with. Columns not listed are written in full: the shortest
+// representation that reads back as the same float.
+type Precision map[string]int
+
+// ParsePrecision parses a comma-separated list of column=decimals pairs,
+// e.g. "pcr=4,ce_iv=2". An empty spec writes every column in full.
+func ParsePrecision(spec string) (Precision, error) {
+	precision := Precision{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		column, decimals, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("precision %q: want column=decimals", part)
+		}
+		column = strings.TrimSpace(column)
+		if !slices.Contains(header, column) {
+			return nil, fmt.Errorf("precision %q: unknown column %q", part, column)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(decimals))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("precision %q: decimals must be a non-negative integer", part)
+		}
+		precision[column] = n
+	}
+	return precision, nil
+}
+
+// PrecisionFromEnv reads the export precision from CSV_PRECISION.
+func PrecisionFromEnv() (Precision, error) {
+	return ParsePrecision(os.Getenv("CSV_PRECISION"))
+}
+
+// format writes a float column's value at the column's precision.
+func (pr Precision) format(column string, v float64) string {
+	if decimals, ok := pr[column]; ok {
+		return strconv.FormatFloat(v, 'f', decimals, 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatOptional writes an undefined value as an empty cell.
+func (pr Precision) formatOptional(column string, v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return pr.format(column, *v)
+}
+
+// ToCSV renders option chain records as CSV bytes, with float columns at
+// the given precision. Each row carries its expiry's max pain from the
+// matching snapshot's aggregate, if there is one.
+func ToCSV(records []models.ResponsePayload, aggregates []models.ChainAggregate, precision Precision) ([]byte, error) {
+	formatFloat := precision.format
+	formatOptional := precision.formatOptional
+
+	type expiryAt struct {
+		timestamp int64
+		expiry    string
+	}
+	maxPain := make(map[expiryAt]*float64, len(aggregates))
+	for _, a := range aggregates {
+		maxPain[expiryAt{a.Timestamp.Unix(), a.ExpiryDate.Format("2006-01-02")}] = a.MaxPain
+	}
+
 	var buf bytes.Buffer
 	w := csv.NewWriter(&buf)
 
@@ -29,22 +107,53 @@ func ToCSV(records []models.ResponsePayload) ([]byte, error) {
 		row := []string{
 			p.Timestamp.Format(time.RFC3339),
 			p.ExpiryDate.Format("2006-01-02"),
-			formatFloat(p.StrikePrice),
-			formatFloat(p.UnderlyingValue),
-			formatFloat(p.CEOpenInterest),
-			formatFloat(p.CEChangeInOpenInterest),
-			formatFloat(p.CEChangeInOpenInterestPercentage),
+			formatFloat("strike_price", p.StrikePrice),
+			formatFloat("underlying_value", p.UnderlyingValue),
+			formatFloat("ce_oi", p.CEOpenInterest),
+			formatFloat("ce_ch_oi", p.CEChangeInOpenInterest),
+			formatFloat("ce_ch_oi_pct", p.CEChangeInOpenInterestPercentage),
 			strconv.Itoa(p.CETotalTradedVolume),
-			formatFloat(p.CEImpliedVolatility),
-			formatFloat(p.CELastPrice),
-			formatFloat(p.PEOpenInterest),
-			formatFloat(p.PEChangeInOpenInterest),
-			formatFloat(p.PEChangeInOpenInterestPercentage),
+			formatFloat("ce_iv", p.CEImpliedVolatility),
+			formatFloat("ce_ltp", p.CELastPrice),
+			formatFloat("pe_oi", p.PEOpenInterest),
+			formatFloat("pe_ch_oi", p.PEChangeInOpenInterest),
+			formatFloat("pe_ch_oi_pct", p.PEChangeInOpenInterestPercentage),
 			strconv.Itoa(p.PETotalTradedVolume),
-			formatFloat(p.PEImpliedVolatility),
-			formatFloat(p.PELastPrice),
-			formatFloat(p.IntraDayPCR),
-			formatFloat(p.PCR),
+			formatFloat("pe_iv", p.PEImpliedVolatility),
+			formatFloat("pe_ltp", p.PELastPrice),
+			formatOptional("intraday_pcr", p.IntraDayPCR),
+			formatOptional("pcr", p.PCR),
+			formatFloat("ce_bid_price", p.CEBidPrice),
+			strconv.Itoa(p.CEBidQty),
+			formatFloat("ce_ask_price", p.CEAskPrice),
+			strconv.Itoa(p.CEAskQty),
+			formatFloat("pe_bid_price", p.PEBidPrice),
+			strconv.Itoa(p.PEBidQty),
+			formatFloat("pe_ask_price", p.PEAskPrice),
+			strconv.Itoa(p.PEAskQty),
+			formatOptional("ce_delta", p.CEDelta),
+			formatOptional("ce_gamma", p.CEGamma),
+			formatOptional("ce_theta", p.CETheta),
+			formatOptional("ce_vega", p.CEVega),
+			formatOptional("ce_rho", p.CERho),
+			formatOptional("pe_delta", p.PEDelta),
+			formatOptional("pe_gamma", p.PEGamma),
+			formatOptional("pe_theta", p.PETheta),
+			formatOptional("pe_vega", p.PEVega),
+			formatOptional("pe_rho", p.PERho),
+			strconv.FormatBool(p.CEIVComputed),
+			strconv.FormatBool(p.PEIVComputed),
+			formatOptional("max_pain", maxPain[expiryAt{p.Timestamp.Unix(), p.ExpiryDate.Format("2006-01-02")}]),
+			formatFloat("atm_strike", p.ATMStrike),
+			strconv.Itoa(p.StrikesFromATM),
+			string(p.CEMoneyness),
+			string(p.PEMoneyness),
+			formatFloat("ce_change", p.CEPriceChange),
+			formatFloat("pe_change", p.PEPriceChange),
+			string(p.CEBuildup),
+			string(p.PEBuildup),
+			string(p.CEIntervalBuildup),
+			string(p.PEIntervalBuildup),
 		}
 		if err := w.Write(row); err != nil {
 			return nil, fmt.Errorf("failed to write csv row: %w", err)
@@ -58,7 +167,3 @@ func ToCSV(records []models.ResponsePayload) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
-
-func formatFloat(v float64) string {
-	return strconv.FormatFloat(v, 'f', 2, 64)
-}
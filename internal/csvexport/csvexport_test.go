@@ -0,0 +1,104 @@
+package csvexport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"server/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestToCSVPrecision(t *testing.T) {
+	pcr, intradayPCR := 0.004, 1.23456789
+	records := []models.ResponsePayload{{
+		StrikePrice:         24250,
+		PCR:                 &pcr,
+		CEImpliedVolatility: 12.3456,
+		IntraDayPCR:         &intradayPCR,
+	}}
+
+	precision, err := ParsePrecision("ce_iv=2, intraday_pcr=4")
+	if err != nil {
+		t.Fatalf("ParsePrecision: %v", err)
+	}
+
+	data, err := ToCSV(records, nil, precision)
+	if err != nil {
+		t.Fatalf("ToCSV: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for i, column := range rows[0] {
+		got[column] = rows[1][i]
+	}
+
+	want := map[string]string{
+		"strike_price": "24250",
+		"pcr":          "0.004",
+		"ce_iv":        "12.35",
+		"intraday_pcr": "1.2346",
+	}
+	for column, value := range want {
+		if got[column] != value {
+			t.Errorf("%s = %q, want %q", column, got[column], value)
+		}
+	}
+}
+
+func TestParsePrecisionRejectsUnknownColumns(t *testing.T) {
+	for _, spec := range []string{"nope=2", "pcr", "pcr=-1", "pcr=x"} {
+		if _, err := ParsePrecision(spec); err == nil {
+			t.Errorf("ParsePrecision(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestToCSVUndefinedRatioIsEmpty(t *testing.T) {
+	data, err := ToCSV([]models.ResponsePayload{{StrikePrice: 24250}}, nil, nil)
+	if err != nil {
+		t.Fatalf("ToCSV: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, column := range rows[0] {
+		if (column == "pcr" || column == "intraday_pcr") && rows[1][i] != "" {
+			t.Errorf("%s = %q, want an empty cell", column, rows[1][i])
+		}
+	}
+}
+
+func TestToCSVMaxPainFromAggregates(t *testing.T) {
+	ts := time.Date(2026, 7, 17, 10, 0, 0, 0, time.UTC)
+	near := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC)
+	far := time.Date(2026, 7, 30, 0, 0, 0, 0, time.UTC)
+	strike := 24250.0
+
+	records := []models.ResponsePayload{
+		{Timestamp: ts, ExpiryDate: near, StrikePrice: 24200},
+		{Timestamp: ts, ExpiryDate: far, StrikePrice: 24200},
+	}
+	aggregates := []models.ChainAggregate{{Timestamp: ts, ExpiryDate: near, MaxPain: &strike}}
+
+	data, err := ToCSV(records, aggregates, nil)
+	if err != nil {
+		t.Fatalf("ToCSV: %v", err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	column := slices.Index(rows[0], "max_pain")
+	if got := rows[1][column]; got != "24250" {
+		t.Errorf("near expiry max_pain = %q, want 24250", got)
+	}
+	if got := rows[2][column]; got != "" {
+		t.Errorf("far expiry max_pain = %q, want an empty cell", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"server/internal/models"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,45 +20,6 @@ var (
 	pgOnce     sync.Once
 )
 
-// Initializes the option_chain_snapshots table
-func InitOptionChainSnapshotsTable(ctx context.Context, pool *pgxpool.Pool) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS option_chain_snapshots (
-		id SERIAL PRIMARY KEY,
-		timestamp TIMESTAMPTZ DEFAULT NOW(),
-		expiry_date DATE NOT NULL,
-		strike_price NUMERIC(10, 2) NOT NULL,
-		underlying_value NUMERIC(10,2),
-
-		ce_oi BIGINT DEFAULT 0,
-		ce_ch_oi BIGINT DEFAULT 0,
-		ce_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
-		ce_vol BIGINT DEFAULT 0,
-		ce_iv NUMERIC(10,2) DEFAULT 0,
-		ce_ltp NUMERIC(10,2) DEFAULT 0,
-
-		pe_oi BIGINT DEFAULT 0,
-		pe_ch_oi BIGINT DEFAULT 0,
-		pe_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
-		pe_vol BIGINT DEFAULT 0,
-		pe_iv NUMERIC(10,2) DEFAULT 0,
-		pe_ltp NUMERIC(10,2) DEFAULT 0,
-
-		intraday_pcr NUMERIC(10,2),
-		pcr NUMERIC(10,2)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_option_chain_expiry
-	ON option_chain_snapshots(expiry_date);
-	`
-
-	_, err := pool.Exec(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to initialize option_chain_snapshots table: %w", err)
-	}
-	return nil
-}
-
 // Creates singleton DB instance
 func NewDB(ctx context.Context, connString string) (*DB, error) {
 	var err error
@@ -68,9 +30,9 @@ func NewDB(ctx context.Context, connString string) (*DB, error) {
 			return
 		}
 
-		// Initialize table automatically
-		if e := InitOptionChainSnapshotsTable(ctx, pool); e != nil {
-			err = fmt.Errorf("failed to initialize table: %w", e)
+		// Bring the schema up to date automatically
+		if _, e := MigrateUp(ctx, pool); e != nil {
+			err = fmt.Errorf("failed to migrate schema: %w", e)
 			return
 		}
 
@@ -89,15 +51,52 @@ func (db *DB) WriteToDB(ctx context.Context, records *[]models.ResponsePayload)
 				timestamp, expiry_date, strike_price, underlying_value,
 				ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
 				pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
-				intraday_pcr, pcr
-			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)
+				intraday_pcr, pcr, symbol,
+				ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+				pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+				ce_delta, ce_gamma, ce_theta, ce_vega, ce_rho,
+				pe_delta, pe_gamma, pe_theta, pe_vega, pe_rho,
+				ce_iv_computed, pe_iv_computed,
+				ce_change, pe_change, ce_buildup, pe_buildup, ce_interval_buildup, pe_interval_buildup,
+				interval_delta, window_deltas
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,
+				$20,$21,$22,$23,$24,$25,$26,$27,
+				$28,$29,$30,$31,$32,$33,$34,$35,$36,$37,$38,$39,
+				$40,$41,$42,$43,$44,$45,$46,$47)
+			ON CONFLICT (symbol, timestamp, expiry_date, strike_price) DO UPDATE SET
+				underlying_value = EXCLUDED.underlying_value,
+				ce_oi = EXCLUDED.ce_oi, ce_ch_oi = EXCLUDED.ce_ch_oi, ce_ch_oi_pct = EXCLUDED.ce_ch_oi_pct,
+				ce_vol = EXCLUDED.ce_vol, ce_iv = EXCLUDED.ce_iv, ce_ltp = EXCLUDED.ce_ltp,
+				pe_oi = EXCLUDED.pe_oi, pe_ch_oi = EXCLUDED.pe_ch_oi, pe_ch_oi_pct = EXCLUDED.pe_ch_oi_pct,
+				pe_vol = EXCLUDED.pe_vol, pe_iv = EXCLUDED.pe_iv, pe_ltp = EXCLUDED.pe_ltp,
+				intraday_pcr = EXCLUDED.intraday_pcr, pcr = EXCLUDED.pcr,
+				ce_bid_price = EXCLUDED.ce_bid_price, ce_bid_qty = EXCLUDED.ce_bid_qty,
+				ce_ask_price = EXCLUDED.ce_ask_price, ce_ask_qty = EXCLUDED.ce_ask_qty,
+				pe_bid_price = EXCLUDED.pe_bid_price, pe_bid_qty = EXCLUDED.pe_bid_qty,
+				pe_ask_price = EXCLUDED.pe_ask_price, pe_ask_qty = EXCLUDED.pe_ask_qty,
+				ce_delta = EXCLUDED.ce_delta, ce_gamma = EXCLUDED.ce_gamma, ce_theta = EXCLUDED.ce_theta,
+				ce_vega = EXCLUDED.ce_vega, ce_rho = EXCLUDED.ce_rho,
+				pe_delta = EXCLUDED.pe_delta, pe_gamma = EXCLUDED.pe_gamma, pe_theta = EXCLUDED.pe_theta,
+				pe_vega = EXCLUDED.pe_vega, pe_rho = EXCLUDED.pe_rho,
+				ce_iv_computed = EXCLUDED.ce_iv_computed, pe_iv_computed = EXCLUDED.pe_iv_computed,
+				ce_change = EXCLUDED.ce_change, pe_change = EXCLUDED.pe_change,
+				ce_buildup = EXCLUDED.ce_buildup, pe_buildup = EXCLUDED.pe_buildup,
+				ce_interval_buildup = EXCLUDED.ce_interval_buildup, pe_interval_buildup = EXCLUDED.pe_interval_buildup,
+				interval_delta = EXCLUDED.interval_delta, window_deltas = EXCLUDED.window_deltas
 		`,
 			p.Timestamp, p.ExpiryDate, p.StrikePrice, p.UnderlyingValue,
 			p.CEOpenInterest, p.CEChangeInOpenInterest, p.CEChangeInOpenInterestPercentage,
 			p.CETotalTradedVolume, p.CEImpliedVolatility, p.CELastPrice,
 			p.PEOpenInterest, p.PEChangeInOpenInterest, p.PEChangeInOpenInterestPercentage,
 			p.PETotalTradedVolume, p.PEImpliedVolatility, p.PELastPrice,
-			p.IntraDayPCR, p.PCR,
+			p.IntraDayPCR, p.PCR, p.Symbol,
+			p.CEBidPrice, p.CEBidQty, p.CEAskPrice, p.CEAskQty,
+			p.PEBidPrice, p.PEBidQty, p.PEAskPrice, p.PEAskQty,
+			p.CEDelta, p.CEGamma, p.CETheta, p.CEVega, p.CERho,
+			p.PEDelta, p.PEGamma, p.PETheta, p.PEVega, p.PERho,
+			p.CEIVComputed, p.PEIVComputed,
+			p.CEPriceChange, p.PEPriceChange, p.CEBuildup, p.PEBuildup, p.CEIntervalBuildup, p.PEIntervalBuildup,
+			p.Interval, p.Windows,
 		)
 	}
 
@@ -107,3 +106,196 @@ func (db *DB) WriteToDB(ctx context.Context, records *[]models.ResponsePayload)
 	}
 	return nil
 }
+
+// Writes chain-level summaries to DB
+func (db *DB) WriteSummaries(ctx context.Context, summaries []models.ChainSummary) error {
+	batch := &pgx.Batch{}
+
+	for _, s := range summaries {
+		batch.Queue(`
+			INSERT INTO option_chain_summaries (
+				symbol, timestamp, expiry_date, underlying_value,
+				ce_tot_oi, ce_tot_vol, pe_tot_oi, pe_tot_vol,
+				pcr, volume_pcr, strike_prices
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+			ON CONFLICT (symbol, timestamp, expiry_date) DO UPDATE SET
+				underlying_value = EXCLUDED.underlying_value,
+				ce_tot_oi = EXCLUDED.ce_tot_oi, ce_tot_vol = EXCLUDED.ce_tot_vol,
+				pe_tot_oi = EXCLUDED.pe_tot_oi, pe_tot_vol = EXCLUDED.pe_tot_vol,
+				pcr = EXCLUDED.pcr, volume_pcr = EXCLUDED.volume_pcr,
+				strike_prices = EXCLUDED.strike_prices
+		`,
+			s.Symbol, s.Timestamp, s.ExpiryDate, s.UnderlyingValue,
+			s.CETotalOI, s.CETotalVolume, s.PETotalOI, s.PETotalVolume,
+			s.PCR, s.VolumePCR, []float64(s.StrikePrices),
+		)
+	}
+
+	br := db.db.SendBatch(ctx, batch)
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("summary batch insert failed: %w", err)
+	}
+	return nil
+}
+
+// Reads back one symbol's option chain rows with timestamps in [from, to),
+// in the order they were written
+func (db *DB) ReadDay(ctx context.Context, symbol string, from, to time.Time) ([]models.ResponsePayload, error) {
+	rows, err := db.db.Query(ctx, `
+		SELECT
+			symbol, timestamp, expiry_date, strike_price::float8, COALESCE(underlying_value, 0)::float8,
+			ce_oi::float8, ce_ch_oi::float8, ce_ch_oi_pct::float8, ce_vol, ce_iv::float8, ce_ltp::float8,
+			ce_bid_price::float8, ce_bid_qty, ce_ask_price::float8, ce_ask_qty,
+			pe_oi::float8, pe_ch_oi::float8, pe_ch_oi_pct::float8, pe_vol, pe_iv::float8, pe_ltp::float8,
+			pe_bid_price::float8, pe_bid_qty, pe_ask_price::float8, pe_ask_qty,
+			intraday_pcr::float8, pcr::float8,
+			ce_delta::float8, ce_gamma::float8, ce_theta::float8, ce_vega::float8, ce_rho::float8,
+			pe_delta::float8, pe_gamma::float8, pe_theta::float8, pe_vega::float8, pe_rho::float8,
+			ce_iv_computed, pe_iv_computed,
+			COALESCE(ce_change, 0)::float8, COALESCE(pe_change, 0)::float8,
+			COALESCE(ce_buildup, ''), COALESCE(pe_buildup, ''),
+			COALESCE(ce_interval_buildup, ''), COALESCE(pe_interval_buildup, ''),
+			interval_delta, window_deltas
+		FROM option_chain_snapshots
+		WHERE symbol = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY id
+	`, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read option chain rows: %w", err)
+	}
+	defer rows.Close()
+
+	var records []models.ResponsePayload
+	for rows.Next() {
+		var p models.ResponsePayload
+		if err := rows.Scan(
+			&p.Symbol, &p.Timestamp, &p.ExpiryDate, &p.StrikePrice, &p.UnderlyingValue,
+			&p.CEOpenInterest, &p.CEChangeInOpenInterest, &p.CEChangeInOpenInterestPercentage,
+			&p.CETotalTradedVolume, &p.CEImpliedVolatility, &p.CELastPrice,
+			&p.CEBidPrice, &p.CEBidQty, &p.CEAskPrice, &p.CEAskQty,
+			&p.PEOpenInterest, &p.PEChangeInOpenInterest, &p.PEChangeInOpenInterestPercentage,
+			&p.PETotalTradedVolume, &p.PEImpliedVolatility, &p.PELastPrice,
+			&p.PEBidPrice, &p.PEBidQty, &p.PEAskPrice, &p.PEAskQty,
+			&p.IntraDayPCR, &p.PCR,
+			&p.CEDelta, &p.CEGamma, &p.CETheta, &p.CEVega, &p.CERho,
+			&p.PEDelta, &p.PEGamma, &p.PETheta, &p.PEVega, &p.PERho,
+			&p.CEIVComputed, &p.PEIVComputed,
+			&p.CEPriceChange, &p.PEPriceChange, &p.CEBuildup, &p.PEBuildup, &p.CEIntervalBuildup, &p.PEIntervalBuildup,
+			&p.Interval, &p.Windows,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan option chain row: %w", err)
+		}
+		p.Timestamp, p.ExpiryDate = inLocation(p.Timestamp, p.ExpiryDate, from.Location())
+		records = append(records, p)
+	}
+	return records, rows.Err()
+}
+
+// Reads back one symbol's chain summaries with timestamps in [from, to)
+func (db *DB) ReadSummaries(ctx context.Context, symbol string, from, to time.Time) ([]models.ChainSummary, error) {
+	rows, err := db.db.Query(ctx, `
+		SELECT
+			symbol, timestamp, expiry_date, COALESCE(underlying_value, 0)::float8,
+			ce_tot_oi::float8, ce_tot_vol::float8, pe_tot_oi::float8, pe_tot_vol::float8,
+			pcr::float8, volume_pcr::float8,
+			COALESCE(strike_prices, '{}')::float8[]
+		FROM option_chain_summaries
+		WHERE symbol = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY id
+	`, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain summaries: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []models.ChainSummary
+	for rows.Next() {
+		var s models.ChainSummary
+		if err := rows.Scan(
+			&s.Symbol, &s.Timestamp, &s.ExpiryDate, &s.UnderlyingValue,
+			&s.CETotalOI, &s.CETotalVolume, &s.PETotalOI, &s.PETotalVolume,
+			&s.PCR, &s.VolumePCR, &s.StrikePrices,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chain summary: %w", err)
+		}
+		s.Timestamp, s.ExpiryDate = inLocation(s.Timestamp, s.ExpiryDate, from.Location())
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// inLocation moves a scanned timestamp into loc and re-anchors a scanned
+// DATE, which comes back as UTC midnight, to midnight in loc, matching how
+// the processor built the rows before they were written
+func inLocation(timestamp, date time.Time, loc *time.Location) (time.Time, time.Time) {
+	return timestamp.In(loc), time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
+
+// Writes chain aggregates to DB
+func (db *DB) WriteAggregates(ctx context.Context, aggregates []models.ChainAggregate) error {
+	batch := &pgx.Batch{}
+
+	for _, a := range aggregates {
+		batch.Queue(`
+			INSERT INTO option_chain_aggregates (
+				symbol, timestamp, expiry_date, underlying_value, atm_strike,
+				ce_tot_oi, pe_tot_oi, ce_tot_ch_oi, pe_tot_ch_oi, ce_tot_vol, pe_tot_vol,
+				pcr, ch_oi_pcr, volume_pcr, atm_window, atm_window_pcr, max_pain
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
+			ON CONFLICT (symbol, timestamp, expiry_date) DO UPDATE SET
+				underlying_value = EXCLUDED.underlying_value, atm_strike = EXCLUDED.atm_strike,
+				ce_tot_oi = EXCLUDED.ce_tot_oi, pe_tot_oi = EXCLUDED.pe_tot_oi,
+				ce_tot_ch_oi = EXCLUDED.ce_tot_ch_oi, pe_tot_ch_oi = EXCLUDED.pe_tot_ch_oi,
+				ce_tot_vol = EXCLUDED.ce_tot_vol, pe_tot_vol = EXCLUDED.pe_tot_vol,
+				pcr = EXCLUDED.pcr, ch_oi_pcr = EXCLUDED.ch_oi_pcr, volume_pcr = EXCLUDED.volume_pcr,
+				atm_window = EXCLUDED.atm_window, atm_window_pcr = EXCLUDED.atm_window_pcr,
+				max_pain = EXCLUDED.max_pain
+		`,
+			a.Symbol, a.Timestamp, a.ExpiryDate, a.UnderlyingValue, a.ATMStrike,
+			a.CETotalOI, a.PETotalOI, a.CETotalChangeOI, a.PETotalChangeOI,
+			a.CETotalVolume, a.PETotalVolume,
+			a.PCR, a.ChangeInOIPCR, a.VolumePCR, a.ATMWindow, a.ATMWindowPCR, a.MaxPain,
+		)
+	}
+
+	br := db.db.SendBatch(ctx, batch)
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("aggregate batch insert failed: %w", err)
+	}
+	return nil
+}
+
+// Reads back one symbol's chain aggregates with timestamps in [from, to)
+func (db *DB) ReadAggregates(ctx context.Context, symbol string, from, to time.Time) ([]models.ChainAggregate, error) {
+	rows, err := db.db.Query(ctx, `
+		SELECT
+			symbol, timestamp, expiry_date, COALESCE(underlying_value, 0)::float8, COALESCE(atm_strike, 0)::float8,
+			ce_tot_oi::float8, pe_tot_oi::float8, ce_tot_ch_oi::float8, pe_tot_ch_oi::float8, ce_tot_vol, pe_tot_vol,
+			pcr::float8, ch_oi_pcr::float8, volume_pcr::float8, atm_window, atm_window_pcr::float8,
+			max_pain::float8
+		FROM option_chain_aggregates
+		WHERE symbol = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY id
+	`, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain aggregates: %w", err)
+	}
+	defer rows.Close()
+
+	var aggregates []models.ChainAggregate
+	for rows.Next() {
+		var a models.ChainAggregate
+		if err := rows.Scan(
+			&a.Symbol, &a.Timestamp, &a.ExpiryDate, &a.UnderlyingValue, &a.ATMStrike,
+			&a.CETotalOI, &a.PETotalOI, &a.CETotalChangeOI, &a.PETotalChangeOI,
+			&a.CETotalVolume, &a.PETotalVolume,
+			&a.PCR, &a.ChangeInOIPCR, &a.VolumePCR, &a.ATMWindow, &a.ATMWindowPCR,
+			&a.MaxPain,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan chain aggregate: %w", err)
+		}
+		a.Timestamp, a.ExpiryDate = inLocation(a.Timestamp, a.ExpiryDate, from.Location())
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, rows.Err()
+}
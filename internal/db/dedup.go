@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// uniqueViolation is Postgres' SQLSTATE for unique_violation.
+const uniqueViolation = "23505"
+
+// DedupResult reports how many duplicate rows a dedup pass found per table.
+type DedupResult struct {
+	Snapshots int64
+	Summaries int64
+}
+
+// Removes rows sharing a natural key, keeping the most recently written
+// (highest id) row of each, so the unique keys can be built on databases
+// written before they existed. With dryRun the duplicates are only counted.
+func DedupOptionChain(ctx context.Context, pool *pgxpool.Pool, dryRun bool) (DedupResult, error) {
+	var result DedupResult
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to begin dedup: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result.Snapshots, err = dedupTable(ctx, tx, "option_chain_snapshots",
+		"symbol, timestamp, expiry_date, strike_price", dryRun)
+	if err != nil {
+		return result, err
+	}
+
+	result.Summaries, err = dedupTable(ctx, tx, "option_chain_summaries",
+		"symbol, timestamp, expiry_date", dryRun)
+	if err != nil {
+		return result, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit dedup: %w", err)
+	}
+	return result, nil
+}
+
+// dedupTable deletes, or with dryRun counts, every row of table that has a
+// later row with the same key columns. Missing tables count as clean.
+func dedupTable(ctx context.Context, tx pgx.Tx, table, key string, dryRun bool) (int64, error) {
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to look up %s: %w", table, err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	duplicates := fmt.Sprintf(`
+		SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY id DESC) AS rn
+			FROM %s
+		) ranked
+		WHERE rn > 1
+	`, key, table)
+
+	if dryRun {
+		var count int64
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM (`+duplicates+`) d`).Scan(&count); err != nil {
+			return 0, fmt.Errorf("failed to count duplicates in %s: %w", table, err)
+		}
+		return count, nil
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, table, duplicates))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete duplicates from %s: %w", table, err)
+	}
+	return tag.RowsAffected(), nil
+}
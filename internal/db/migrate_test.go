@@ -0,0 +1,29 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrationsAreOrderedAndReversible(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d", i, m.Version)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has an empty up or down script", m.Version, m.Name)
+		}
+	}
+
+	if got := migrations[0].Name; got != "create_option_chain_snapshots" {
+		t.Errorf("first migration is %q, want the original table", got)
+	}
+}
@@ -0,0 +1,29 @@
+-- The original schema. IF NOT EXISTS lets databases created before
+-- migrations existed adopt this as their first version.
+CREATE TABLE IF NOT EXISTS option_chain_snapshots (
+	id SERIAL PRIMARY KEY,
+	timestamp TIMESTAMPTZ DEFAULT NOW(),
+	expiry_date DATE NOT NULL,
+	strike_price NUMERIC(10, 2) NOT NULL,
+	underlying_value NUMERIC(10,2),
+
+	ce_oi BIGINT DEFAULT 0,
+	ce_ch_oi BIGINT DEFAULT 0,
+	ce_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	ce_vol BIGINT DEFAULT 0,
+	ce_iv NUMERIC(10,2) DEFAULT 0,
+	ce_ltp NUMERIC(10,2) DEFAULT 0,
+
+	pe_oi BIGINT DEFAULT 0,
+	pe_ch_oi BIGINT DEFAULT 0,
+	pe_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	pe_vol BIGINT DEFAULT 0,
+	pe_iv NUMERIC(10,2) DEFAULT 0,
+	pe_ltp NUMERIC(10,2) DEFAULT 0,
+
+	intraday_pcr NUMERIC(10,2),
+	pcr NUMERIC(10,2)
+);
+
+CREATE INDEX IF NOT EXISTS idx_option_chain_expiry
+ON option_chain_snapshots(expiry_date);
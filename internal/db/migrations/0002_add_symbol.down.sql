@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_option_chain_symbol_timestamp;
+
+ALTER TABLE option_chain_snapshots DROP COLUMN IF EXISTS symbol;
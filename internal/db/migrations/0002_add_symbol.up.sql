@@ -0,0 +1,6 @@
+-- Tables created before multi-symbol support only ever held NIFTY.
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS symbol TEXT NOT NULL DEFAULT 'NIFTY';
+
+CREATE INDEX IF NOT EXISTS idx_option_chain_symbol_timestamp
+ON option_chain_snapshots(symbol, timestamp);
@@ -0,0 +1,9 @@
+ALTER TABLE option_chain_snapshots
+DROP COLUMN IF EXISTS ce_bid_price,
+DROP COLUMN IF EXISTS ce_bid_qty,
+DROP COLUMN IF EXISTS ce_ask_price,
+DROP COLUMN IF EXISTS ce_ask_qty,
+DROP COLUMN IF EXISTS pe_bid_price,
+DROP COLUMN IF EXISTS pe_bid_qty,
+DROP COLUMN IF EXISTS pe_ask_price,
+DROP COLUMN IF EXISTS pe_ask_qty;
@@ -0,0 +1,9 @@
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS ce_bid_price NUMERIC(10,2) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS ce_bid_qty BIGINT DEFAULT 0,
+ADD COLUMN IF NOT EXISTS ce_ask_price NUMERIC(10,2) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS ce_ask_qty BIGINT DEFAULT 0,
+ADD COLUMN IF NOT EXISTS pe_bid_price NUMERIC(10,2) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS pe_bid_qty BIGINT DEFAULT 0,
+ADD COLUMN IF NOT EXISTS pe_ask_price NUMERIC(10,2) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS pe_ask_qty BIGINT DEFAULT 0;
@@ -0,0 +1,20 @@
+-- Chain-level totals NSE reports per expiry, one row per snapshot.
+CREATE TABLE IF NOT EXISTS option_chain_summaries (
+	id SERIAL PRIMARY KEY,
+	symbol TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	expiry_date DATE NOT NULL,
+	underlying_value NUMERIC(10,2),
+
+	ce_tot_oi BIGINT DEFAULT 0,
+	ce_tot_vol BIGINT DEFAULT 0,
+	pe_tot_oi BIGINT DEFAULT 0,
+	pe_tot_vol BIGINT DEFAULT 0,
+
+	pcr NUMERIC(10,2),
+	volume_pcr NUMERIC(10,2),
+	strike_prices NUMERIC[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_option_chain_summaries_symbol_timestamp
+ON option_chain_summaries(symbol, timestamp);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uq_option_chain_summaries_key;
+
+DROP INDEX IF EXISTS uq_option_chain_snapshots_key;
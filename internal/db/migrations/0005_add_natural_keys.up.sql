@@ -0,0 +1,7 @@
+-- The natural keys writes upsert on. Fails on databases still holding
+-- duplicates from before the keys existed; run `processing dedup` first.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_option_chain_snapshots_key
+ON option_chain_snapshots(symbol, timestamp, expiry_date, strike_price);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_option_chain_summaries_key
+ON option_chain_summaries(symbol, timestamp, expiry_date);
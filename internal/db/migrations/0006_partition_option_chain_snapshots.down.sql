@@ -0,0 +1,100 @@
+-- Fold the partitions back into a single table, along with any rows the up
+-- migration set aside in option_chain_snapshots_untimed. Rows in partitions
+-- detached by retention are not brought back.
+ALTER TABLE option_chain_snapshots RENAME TO option_chain_snapshots_partitioned;
+ALTER TABLE option_chain_snapshots_partitioned
+RENAME CONSTRAINT option_chain_snapshots_pkey TO option_chain_snapshots_partitioned_pkey;
+
+DROP INDEX IF EXISTS idx_option_chain_expiry;
+DROP INDEX IF EXISTS idx_option_chain_symbol_timestamp;
+DROP INDEX IF EXISTS uq_option_chain_snapshots_key;
+
+CREATE TABLE option_chain_snapshots (
+	id SERIAL PRIMARY KEY,
+	symbol TEXT NOT NULL DEFAULT 'NIFTY',
+	timestamp TIMESTAMPTZ DEFAULT NOW(),
+	expiry_date DATE NOT NULL,
+	strike_price NUMERIC(10, 2) NOT NULL,
+	underlying_value NUMERIC(10,2),
+
+	ce_oi BIGINT DEFAULT 0,
+	ce_ch_oi BIGINT DEFAULT 0,
+	ce_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	ce_vol BIGINT DEFAULT 0,
+	ce_iv NUMERIC(10,2) DEFAULT 0,
+	ce_ltp NUMERIC(10,2) DEFAULT 0,
+	ce_bid_price NUMERIC(10,2) DEFAULT 0,
+	ce_bid_qty BIGINT DEFAULT 0,
+	ce_ask_price NUMERIC(10,2) DEFAULT 0,
+	ce_ask_qty BIGINT DEFAULT 0,
+
+	pe_oi BIGINT DEFAULT 0,
+	pe_ch_oi BIGINT DEFAULT 0,
+	pe_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	pe_vol BIGINT DEFAULT 0,
+	pe_iv NUMERIC(10,2) DEFAULT 0,
+	pe_ltp NUMERIC(10,2) DEFAULT 0,
+	pe_bid_price NUMERIC(10,2) DEFAULT 0,
+	pe_bid_qty BIGINT DEFAULT 0,
+	pe_ask_price NUMERIC(10,2) DEFAULT 0,
+	pe_ask_qty BIGINT DEFAULT 0,
+
+	intraday_pcr NUMERIC(10,2),
+	pcr NUMERIC(10,2)
+);
+
+INSERT INTO option_chain_snapshots (
+	id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+	ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+	ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+	pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+	pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+	intraday_pcr, pcr
+)
+SELECT
+	id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+	ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+	ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+	pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+	pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+	intraday_pcr, pcr
+FROM option_chain_snapshots_partitioned;
+
+DO $$
+BEGIN
+	IF to_regclass('option_chain_snapshots_untimed') IS NOT NULL THEN
+		INSERT INTO option_chain_snapshots (
+			id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+			ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+			ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+			pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+			pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+			intraday_pcr, pcr
+		)
+		SELECT
+			id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+			ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+			ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+			pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+			pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+			intraday_pcr, pcr
+		FROM option_chain_snapshots_untimed;
+		DROP TABLE option_chain_snapshots_untimed;
+	END IF;
+END $$;
+
+SELECT setval(
+	pg_get_serial_sequence('option_chain_snapshots', 'id'),
+	COALESCE((SELECT MAX(id) FROM option_chain_snapshots), 0) + 1,
+	false);
+
+DROP TABLE option_chain_snapshots_partitioned;
+
+CREATE INDEX IF NOT EXISTS idx_option_chain_expiry
+ON option_chain_snapshots(expiry_date);
+
+CREATE INDEX IF NOT EXISTS idx_option_chain_symbol_timestamp
+ON option_chain_snapshots(symbol, timestamp);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_option_chain_snapshots_key
+ON option_chain_snapshots(symbol, timestamp, expiry_date, strike_price);
@@ -0,0 +1,119 @@
+-- Range-partition option_chain_snapshots by IST trading date, one
+-- partition per day named option_chain_snapshots_YYYYMMDD. Existing rows
+-- are copied into partitions created for the days they cover; the
+-- processor creates upcoming partitions itself. Partition keys must be part
+-- of every unique key, so the primary key becomes (id, timestamp).
+-- Rows with a NULL timestamp, which the original schema allowed, fit no
+-- partition; they are moved to option_chain_snapshots_untimed, created only
+-- if there are any, with a warning, instead of being dropped.
+ALTER TABLE option_chain_snapshots RENAME TO option_chain_snapshots_legacy;
+ALTER TABLE option_chain_snapshots_legacy
+RENAME CONSTRAINT option_chain_snapshots_pkey TO option_chain_snapshots_legacy_pkey;
+
+DROP INDEX IF EXISTS idx_option_chain_expiry;
+DROP INDEX IF EXISTS idx_option_chain_symbol_timestamp;
+DROP INDEX IF EXISTS uq_option_chain_snapshots_key;
+
+CREATE TABLE option_chain_snapshots (
+	id BIGSERIAL,
+	symbol TEXT NOT NULL DEFAULT 'NIFTY',
+	timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	expiry_date DATE NOT NULL,
+	strike_price NUMERIC(10, 2) NOT NULL,
+	underlying_value NUMERIC(10,2),
+
+	ce_oi BIGINT DEFAULT 0,
+	ce_ch_oi BIGINT DEFAULT 0,
+	ce_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	ce_vol BIGINT DEFAULT 0,
+	ce_iv NUMERIC(10,2) DEFAULT 0,
+	ce_ltp NUMERIC(10,2) DEFAULT 0,
+	ce_bid_price NUMERIC(10,2) DEFAULT 0,
+	ce_bid_qty BIGINT DEFAULT 0,
+	ce_ask_price NUMERIC(10,2) DEFAULT 0,
+	ce_ask_qty BIGINT DEFAULT 0,
+
+	pe_oi BIGINT DEFAULT 0,
+	pe_ch_oi BIGINT DEFAULT 0,
+	pe_ch_oi_pct NUMERIC(10,2) DEFAULT 0,
+	pe_vol BIGINT DEFAULT 0,
+	pe_iv NUMERIC(10,2) DEFAULT 0,
+	pe_ltp NUMERIC(10,2) DEFAULT 0,
+	pe_bid_price NUMERIC(10,2) DEFAULT 0,
+	pe_bid_qty BIGINT DEFAULT 0,
+	pe_ask_price NUMERIC(10,2) DEFAULT 0,
+	pe_ask_qty BIGINT DEFAULT 0,
+
+	intraday_pcr NUMERIC(10,2),
+	pcr NUMERIC(10,2),
+
+	PRIMARY KEY (id, timestamp)
+) PARTITION BY RANGE (timestamp);
+
+CREATE INDEX idx_option_chain_expiry
+ON option_chain_snapshots(expiry_date);
+
+CREATE INDEX idx_option_chain_symbol_timestamp
+ON option_chain_snapshots(symbol, timestamp);
+
+CREATE UNIQUE INDEX uq_option_chain_snapshots_key
+ON option_chain_snapshots(symbol, timestamp, expiry_date, strike_price);
+
+-- Catches rows for days whose partition is missing, so a write never fails
+-- on it. The processor warns while it holds rows.
+CREATE TABLE option_chain_snapshots_default
+PARTITION OF option_chain_snapshots DEFAULT;
+
+DO $$
+DECLARE
+	d DATE;
+BEGIN
+	FOR d IN
+		SELECT DISTINCT (timestamp AT TIME ZONE 'Asia/Kolkata')::date
+		FROM option_chain_snapshots_legacy
+		WHERE timestamp IS NOT NULL
+	LOOP
+		EXECUTE format(
+			'CREATE TABLE %I PARTITION OF option_chain_snapshots FOR VALUES FROM (%L) TO (%L)',
+			'option_chain_snapshots_' || to_char(d, 'YYYYMMDD'),
+			d::timestamp AT TIME ZONE 'Asia/Kolkata',
+			(d + 1)::timestamp AT TIME ZONE 'Asia/Kolkata');
+	END LOOP;
+END $$;
+
+INSERT INTO option_chain_snapshots (
+	id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+	ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+	ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+	pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+	pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+	intraday_pcr, pcr
+)
+SELECT
+	id, symbol, timestamp, expiry_date, strike_price, underlying_value,
+	ce_oi, ce_ch_oi, ce_ch_oi_pct, ce_vol, ce_iv, ce_ltp,
+	ce_bid_price, ce_bid_qty, ce_ask_price, ce_ask_qty,
+	pe_oi, pe_ch_oi, pe_ch_oi_pct, pe_vol, pe_iv, pe_ltp,
+	pe_bid_price, pe_bid_qty, pe_ask_price, pe_ask_qty,
+	intraday_pcr, pcr
+FROM option_chain_snapshots_legacy
+WHERE timestamp IS NOT NULL;
+
+SELECT setval(
+	pg_get_serial_sequence('option_chain_snapshots', 'id'),
+	COALESCE((SELECT MAX(id) FROM option_chain_snapshots), 0) + 1,
+	false);
+
+DO $$
+DECLARE
+	untimed BIGINT;
+BEGIN
+	SELECT COUNT(*) INTO untimed FROM option_chain_snapshots_legacy WHERE timestamp IS NULL;
+	IF untimed > 0 THEN
+		CREATE TABLE option_chain_snapshots_untimed AS
+		SELECT * FROM option_chain_snapshots_legacy WHERE timestamp IS NULL;
+		RAISE WARNING '% rows with no timestamp kept in option_chain_snapshots_untimed', untimed;
+	END IF;
+END $$;
+
+DROP TABLE option_chain_snapshots_legacy;
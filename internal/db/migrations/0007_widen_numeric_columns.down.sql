@@ -0,0 +1,21 @@
+-- Rounds every value back to 2 decimals; the dropped digits are lost.
+ALTER TABLE option_chain_summaries
+ALTER COLUMN underlying_value TYPE NUMERIC(10,2),
+ALTER COLUMN pcr TYPE NUMERIC(10,2),
+ALTER COLUMN volume_pcr TYPE NUMERIC(10,2);
+
+ALTER TABLE option_chain_snapshots
+ALTER COLUMN strike_price TYPE NUMERIC(10,2),
+ALTER COLUMN underlying_value TYPE NUMERIC(10,2),
+ALTER COLUMN ce_ch_oi_pct TYPE NUMERIC(10,2),
+ALTER COLUMN ce_iv TYPE NUMERIC(10,2),
+ALTER COLUMN ce_ltp TYPE NUMERIC(10,2),
+ALTER COLUMN ce_bid_price TYPE NUMERIC(10,2),
+ALTER COLUMN ce_ask_price TYPE NUMERIC(10,2),
+ALTER COLUMN pe_ch_oi_pct TYPE NUMERIC(10,2),
+ALTER COLUMN pe_iv TYPE NUMERIC(10,2),
+ALTER COLUMN pe_ltp TYPE NUMERIC(10,2),
+ALTER COLUMN pe_bid_price TYPE NUMERIC(10,2),
+ALTER COLUMN pe_ask_price TYPE NUMERIC(10,2),
+ALTER COLUMN intraday_pcr TYPE NUMERIC(10,2),
+ALTER COLUMN pcr TYPE NUMERIC(10,2);
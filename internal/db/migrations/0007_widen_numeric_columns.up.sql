@@ -0,0 +1,23 @@
+-- Unconstrained NUMERIC keeps every digit NSE sends and every ratio the
+-- processor computes. Widening from NUMERIC(10,2) loses nothing and, on
+-- the partitioned table, carries through to every attached partition.
+ALTER TABLE option_chain_snapshots
+ALTER COLUMN strike_price TYPE NUMERIC,
+ALTER COLUMN underlying_value TYPE NUMERIC,
+ALTER COLUMN ce_ch_oi_pct TYPE NUMERIC,
+ALTER COLUMN ce_iv TYPE NUMERIC,
+ALTER COLUMN ce_ltp TYPE NUMERIC,
+ALTER COLUMN ce_bid_price TYPE NUMERIC,
+ALTER COLUMN ce_ask_price TYPE NUMERIC,
+ALTER COLUMN pe_ch_oi_pct TYPE NUMERIC,
+ALTER COLUMN pe_iv TYPE NUMERIC,
+ALTER COLUMN pe_ltp TYPE NUMERIC,
+ALTER COLUMN pe_bid_price TYPE NUMERIC,
+ALTER COLUMN pe_ask_price TYPE NUMERIC,
+ALTER COLUMN intraday_pcr TYPE NUMERIC,
+ALTER COLUMN pcr TYPE NUMERIC;
+
+ALTER TABLE option_chain_summaries
+ALTER COLUMN underlying_value TYPE NUMERIC,
+ALTER COLUMN pcr TYPE NUMERIC,
+ALTER COLUMN volume_pcr TYPE NUMERIC;
@@ -0,0 +1,5 @@
+UPDATE option_chain_summaries SET volume_pcr = -1 WHERE ce_tot_vol = 0 AND volume_pcr IS NULL;
+UPDATE option_chain_summaries SET pcr = -1 WHERE ce_tot_oi = 0 AND pcr IS NULL;
+
+UPDATE option_chain_snapshots SET intraday_pcr = -1 WHERE ce_ch_oi = 0 AND intraday_pcr IS NULL;
+UPDATE option_chain_snapshots SET pcr = -1 WHERE ce_oi = 0 AND pcr IS NULL;
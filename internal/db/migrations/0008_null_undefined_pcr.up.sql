@@ -0,0 +1,8 @@
+-- Ratios used to be stored as -1 when their denominator was zero. Only
+-- rows with a zero denominator are touched, since -1 is also a legitimate
+-- intraday PCR when the changes in OI have opposite signs.
+UPDATE option_chain_snapshots SET pcr = NULL WHERE ce_oi = 0 AND pcr = -1;
+UPDATE option_chain_snapshots SET intraday_pcr = NULL WHERE ce_ch_oi = 0 AND intraday_pcr = -1;
+
+UPDATE option_chain_summaries SET pcr = NULL WHERE ce_tot_oi = 0 AND pcr = -1;
+UPDATE option_chain_summaries SET volume_pcr = NULL WHERE ce_tot_vol = 0 AND volume_pcr = -1;
@@ -0,0 +1,26 @@
+-- Per-expiry aggregates the processor computes from each snapshot's
+-- strikes, one row per snapshot and expiry.
+CREATE TABLE IF NOT EXISTS option_chain_aggregates (
+	id BIGSERIAL PRIMARY KEY,
+	symbol TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	expiry_date DATE NOT NULL,
+	underlying_value NUMERIC,
+	atm_strike NUMERIC,
+
+	ce_tot_oi BIGINT DEFAULT 0,
+	pe_tot_oi BIGINT DEFAULT 0,
+	ce_tot_ch_oi BIGINT DEFAULT 0,
+	pe_tot_ch_oi BIGINT DEFAULT 0,
+	ce_tot_vol BIGINT DEFAULT 0,
+	pe_tot_vol BIGINT DEFAULT 0,
+
+	pcr NUMERIC,
+	ch_oi_pcr NUMERIC,
+	volume_pcr NUMERIC,
+	atm_window INTEGER NOT NULL,
+	atm_window_pcr NUMERIC
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_option_chain_aggregates_key
+ON option_chain_aggregates(symbol, timestamp, expiry_date);
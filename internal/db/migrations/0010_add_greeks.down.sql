@@ -0,0 +1,11 @@
+ALTER TABLE option_chain_snapshots
+DROP COLUMN IF EXISTS ce_delta,
+DROP COLUMN IF EXISTS ce_gamma,
+DROP COLUMN IF EXISTS ce_theta,
+DROP COLUMN IF EXISTS ce_vega,
+DROP COLUMN IF EXISTS ce_rho,
+DROP COLUMN IF EXISTS pe_delta,
+DROP COLUMN IF EXISTS pe_gamma,
+DROP COLUMN IF EXISTS pe_theta,
+DROP COLUMN IF EXISTS pe_vega,
+DROP COLUMN IF EXISTS pe_rho;
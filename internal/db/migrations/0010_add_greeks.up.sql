@@ -0,0 +1,12 @@
+-- Greeks computed by the processor, NULL where the option has no IV.
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS ce_delta NUMERIC,
+ADD COLUMN IF NOT EXISTS ce_gamma NUMERIC,
+ADD COLUMN IF NOT EXISTS ce_theta NUMERIC,
+ADD COLUMN IF NOT EXISTS ce_vega NUMERIC,
+ADD COLUMN IF NOT EXISTS ce_rho NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_delta NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_gamma NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_theta NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_vega NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_rho NUMERIC;
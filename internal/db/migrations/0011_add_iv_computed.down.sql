@@ -0,0 +1,3 @@
+ALTER TABLE option_chain_snapshots
+DROP COLUMN IF EXISTS ce_iv_computed,
+DROP COLUMN IF EXISTS pe_iv_computed;
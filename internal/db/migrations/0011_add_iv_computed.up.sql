@@ -0,0 +1,5 @@
+-- Whether ce_iv/pe_iv was solved from the option's price because NSE
+-- reported no IV. Existing rows all carry NSE's value.
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS ce_iv_computed BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS pe_iv_computed BOOLEAN NOT NULL DEFAULT FALSE;
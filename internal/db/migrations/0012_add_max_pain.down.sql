@@ -0,0 +1,2 @@
+ALTER TABLE option_chain_aggregates
+DROP COLUMN IF EXISTS max_pain;
@@ -0,0 +1,3 @@
+-- Max pain strike per snapshot and expiry, NULL when the expiry had no OI.
+ALTER TABLE option_chain_aggregates
+ADD COLUMN IF NOT EXISTS max_pain NUMERIC;
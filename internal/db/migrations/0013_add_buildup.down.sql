@@ -0,0 +1,7 @@
+ALTER TABLE option_chain_snapshots
+DROP COLUMN IF EXISTS ce_change,
+DROP COLUMN IF EXISTS pe_change,
+DROP COLUMN IF EXISTS ce_buildup,
+DROP COLUMN IF EXISTS pe_buildup,
+DROP COLUMN IF EXISTS ce_interval_buildup,
+DROP COLUMN IF EXISTS pe_interval_buildup;
@@ -0,0 +1,10 @@
+-- Price change since the previous close and the OI buildup read from it,
+-- against the previous close and against the previous snapshot. Buildups
+-- are NULL or empty when price or OI didn't move.
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS ce_change NUMERIC,
+ADD COLUMN IF NOT EXISTS pe_change NUMERIC,
+ADD COLUMN IF NOT EXISTS ce_buildup TEXT,
+ADD COLUMN IF NOT EXISTS pe_buildup TEXT,
+ADD COLUMN IF NOT EXISTS ce_interval_buildup TEXT,
+ADD COLUMN IF NOT EXISTS pe_interval_buildup TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE option_chain_snapshots
+DROP COLUMN IF EXISTS interval_delta,
+DROP COLUMN IF EXISTS window_deltas;
@@ -0,0 +1,6 @@
+-- Moves in OI, volume, LTP and IV since the previous snapshot and over
+-- rolling windows keyed like {"15m": {...}}, NULL where the day had no
+-- earlier snapshot to compare against.
+ALTER TABLE option_chain_snapshots
+ADD COLUMN IF NOT EXISTS interval_delta JSONB,
+ADD COLUMN IF NOT EXISTS window_deltas JSONB;
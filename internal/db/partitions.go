@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	snapshotsTable        = "option_chain_snapshots"
+	partitionPrefix       = snapshotsTable + "_"
+	defaultPartition      = partitionPrefix + "default"
+	partitionSuffixLayout = "20060102"
+)
+
+// Partition is one daily partition of option_chain_snapshots.
+type Partition struct {
+	Name string
+	// Day is midnight of the trading date the partition covers.
+	Day time.Time
+}
+
+// PartitionName returns the name of the partition holding day's rows.
+func PartitionName(day time.Time) string {
+	return partitionPrefix + day.Format(partitionSuffixLayout)
+}
+
+// Creates the daily partitions for the days days starting at from's date,
+// in from's location, returning the names of the ones it created. A day
+// that fails is reported in the returned error without stopping the rest
+func (db *DB) EnsurePartitions(ctx context.Context, from time.Time, days int) ([]string, error) {
+	loc := from.Location()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+
+	var created []string
+	var errs []error
+	for i := range days {
+		day := start.AddDate(0, 0, i)
+		name := PartitionName(day)
+
+		var exists bool
+		if err := db.db.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up partition %s: %w", name, err))
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		if err := db.createPartition(ctx, name, day, day.AddDate(0, 0, 1)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create partition %s: %w", name, err))
+			continue
+		}
+		created = append(created, name)
+	}
+	return created, errors.Join(errs...)
+}
+
+// createPartition creates the partition for [from, to). Postgres refuses
+// to create a partition while the default partition holds rows in its
+// range, so if rows for the day already landed there, the default is
+// detached, the partition created, the rows moved into it and the default
+// reattached, all in one transaction.
+func (db *DB) createPartition(ctx context.Context, name string, from, to time.Time) error {
+	partition := pgx.Identifier{name}.Sanitize()
+	def := pgx.Identifier{defaultPartition}.Sanitize()
+	// DDL takes no parameters; the bounds are formatted timestamps.
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		partition, snapshotsTable, from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	var stray bool
+	err := db.db.QueryRow(ctx, fmt.Sprintf(
+		`SELECT EXISTS (SELECT 1 FROM %s WHERE timestamp >= $1 AND timestamp < $2)`, def),
+		from, to).Scan(&stray)
+	if err != nil {
+		return fmt.Errorf("failed to check default partition: %w", err)
+	}
+	if !stray {
+		_, err := db.db.Exec(ctx, create)
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, db.db, func(tx pgx.Tx) error {
+		statements := []struct {
+			sql  string
+			args []any
+		}{
+			{sql: fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, snapshotsTable, def)},
+			{sql: create},
+			{sql: fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s WHERE timestamp >= $1 AND timestamp < $2`, partition, def),
+				args: []any{from, to}},
+			{sql: fmt.Sprintf(`DELETE FROM %s WHERE timestamp >= $1 AND timestamp < $2`, def),
+				args: []any{from, to}},
+			{sql: fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s DEFAULT`, snapshotsTable, def)},
+		}
+		for _, s := range statements {
+			if _, err := tx.Exec(ctx, s.sql, s.args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Lists the daily partitions attached to option_chain_snapshots, oldest
+// first, with their days in loc
+func (db *DB) Partitions(ctx context.Context, loc *time.Location) ([]Partition, error) {
+	rows, err := db.db.Query(ctx, `
+		SELECT child.relname
+		FROM pg_inherits
+		JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+		JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+		WHERE parent.relname = $1
+	`, snapshotsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	var partitions []Partition
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		day, err := time.ParseInLocation(partitionSuffixLayout, strings.TrimPrefix(name, partitionPrefix), loc)
+		if err != nil {
+			// The default partition, or one not created by EnsurePartitions.
+			continue
+		}
+		partitions = append(partitions, Partition{Name: name, Day: day})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Day.Before(partitions[j].Day) })
+	return partitions, nil
+}
+
+// Counts a partition's rows per symbol
+func (db *DB) PartitionSymbols(ctx context.Context, name string) (map[string]int64, error) {
+	rows, err := db.db.Query(ctx, fmt.Sprintf(`SELECT symbol, COUNT(*) FROM %s GROUP BY symbol`,
+		pgx.Identifier{name}.Sanitize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows in %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var symbol string
+		var count int64
+		if err := rows.Scan(&symbol, &count); err != nil {
+			return nil, err
+		}
+		counts[symbol] = count
+	}
+	return counts, rows.Err()
+}
+
+// Counts the rows that fell into the default partition because their
+// day had no partition
+func (db *DB) DefaultPartitionRows(ctx context.Context) (int64, error) {
+	var count int64
+	err := db.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`,
+		pgx.Identifier{defaultPartition}.Sanitize())).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows in %s: %w", defaultPartition, err)
+	}
+	return count, nil
+}
+
+// Detaches a daily partition from option_chain_snapshots and, unless
+// detachOnly, drops it
+func (db *DB) RemovePartition(ctx context.Context, name string, detachOnly bool) error {
+	_, err := db.db.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`,
+		snapshotsTable, pgx.Identifier{name}.Sanitize()))
+	if err != nil {
+		return fmt.Errorf("failed to detach partition %s: %w", name, err)
+	}
+	if detachOnly {
+		return nil
+	}
+
+	if _, err := db.db.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, pgx.Identifier{name}.Sanitize())); err != nil {
+		return fmt.Errorf("failed to drop partition %s: %w", name, err)
+	}
+	return nil
+}
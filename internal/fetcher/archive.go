@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"server/internal/symbols"
+	"time"
+)
+
+const (
+	contractInfoArchiveName = "contract-info"
+	archivePrefix           = "raw"
+	archiveExt              = ".json.gz"
+)
+
+func optionChainArchiveName(expiry string) string {
+	return "option-chain-" + expiry
+}
+
+// ArchiveStore is where archived responses are written. It has the same
+// shape as the processor's CSVUploader, so *storage.BucketUploader can be
+// used directly; DiskStore writes to a local directory instead.
+type ArchiveStore interface {
+	Upload(ctx context.Context, key string, data []byte) error
+}
+
+// DiskStore is an ArchiveStore that writes each key as a file under Dir.
+type DiskStore struct {
+	Dir string
+}
+
+func (d DiskStore) Upload(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(d.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Archiver keeps every raw contract-info and option-chain-v3 response the
+// sources fetch, gzip-compressed, so history can be reprocessed when the
+// models change. Responses from one fetch share a snapshot directory:
+//
+//	raw/<slug>/<YYYY-MM-DD>/<HHMMSS>/contract-info.json.gz
+//	raw/<slug>/<YYYY-MM-DD>/<HHMMSS>/option-chain-<expiry>.json.gz
+//
+// Archiving is best effort: failures are logged and never fail the fetch.
+type Archiver struct {
+	store  ArchiveStore
+	loc    *time.Location
+	logger *slog.Logger
+}
+
+func NewArchiver(store ArchiveStore, loc *time.Location, logger *slog.Logger) *Archiver {
+	return &Archiver{store: store, loc: loc, logger: logger}
+}
+
+// Archive stores one raw response body. A nil Archiver does nothing.
+func (a *Archiver) Archive(ctx context.Context, sym symbols.Symbol, snapshotAt time.Time, name string, body []byte) {
+	if a == nil {
+		return
+	}
+
+	key := archiveKey(sym, snapshotAt.In(a.loc), name)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		a.logger.Error("Failed to compress raw response", slog.String("key", key), slog.String("err", err.Error()))
+		return
+	}
+	if err := zw.Close(); err != nil {
+		a.logger.Error("Failed to compress raw response", slog.String("key", key), slog.String("err", err.Error()))
+		return
+	}
+
+	if err := a.store.Upload(ctx, key, buf.Bytes()); err != nil {
+		a.logger.Error("Failed to archive raw response", slog.String("key", key), slog.String("err", err.Error()))
+		return
+	}
+	a.logger.Debug("Archived raw response", slog.String("key", key))
+}
+
+func archiveKey(sym symbols.Symbol, at time.Time, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s%s",
+		archivePrefix, sym.Slug(), at.Format("2006-01-02"), at.Format("150405"), name, archiveExt)
+}
@@ -0,0 +1,41 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchivedSnapshotsReplay(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewArchiver(DiskStore{Dir: dir}, time.UTC, discardLogger())
+
+	snapshotAt := time.Date(2026, time.July, 17, 11, 43, 20, 0, time.UTC)
+	ctx := context.Background()
+
+	archiver.Archive(ctx, testSymbol, snapshotAt, contractInfoArchiveName, []byte(`{"expiryDates":["21-Jul-2026","28-Jul-2026"]}`))
+	for _, expiry := range []string{"21-Jul-2026", "28-Jul-2026"} {
+		body, err := json.Marshal(testChain("17-Jul-2026 11:43:19", expiry))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archiver.Archive(ctx, testSymbol, snapshotAt, optionChainArchiveName(expiry), body)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "raw", "nifty50", "2026-07-17", "114320", "*.json.gz")); len(matches) != 3 {
+		t.Fatalf("got archived files %v, want 3", matches)
+	}
+
+	captures, err := LoadCaptures(filepath.Join(dir, "raw"), testSymbol, time.UTC)
+	if err != nil {
+		t.Fatalf("LoadCaptures: %v", err)
+	}
+	if len(captures) != 1 {
+		t.Fatalf("got %d captures, want the two expiries merged into 1", len(captures))
+	}
+	if got := len(captures[0].Chain.Records.Data); got != 4 {
+		t.Errorf("merged snapshot has %d rows, want 4", got)
+	}
+}
@@ -0,0 +1,198 @@
+package fetcher
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const expiryLayout = "02-Jan-2006"
+
+// DefaultExpiryRule matches the original behaviour of capturing the two
+// nearest expiries.
+const DefaultExpiryRule = "next:2"
+
+// ExpiryRule decides which of a symbol's listed expiries are captured on
+// each snapshot. It is a union of terms joined by "+", e.g.
+// "weekly:4+monthly:1" for the next four weeklies plus the current monthly.
+// Supported terms:
+//
+//	all                  every listed expiry
+//	next:N               the N nearest expiries
+//	weekly:N             the N nearest non-monthly expiries
+//	monthly              every monthly expiry
+//	monthly:N            the N nearest monthly expiries
+//	list:D1,D2,...       explicit expiries in DD-Mon-YYYY form
+//
+// A monthly expiry is the last listed expiry in its calendar month. Terms
+// that ask for more expiries than are listed take whatever is available, so
+// near-expiry days with a short catalog still produce a snapshot.
+type ExpiryRule struct {
+	spec  string
+	terms []expiryTerm
+}
+
+type expiryTerm struct {
+	kind  string
+	n     int
+	dates []string
+}
+
+// ParseExpiryRule parses a rule in the syntax described on ExpiryRule.
+func ParseExpiryRule(spec string) (ExpiryRule, error) {
+	rule := ExpiryRule{spec: spec}
+
+	for _, raw := range strings.Split(spec, "+") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		kind, arg, hasArg := strings.Cut(raw, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		arg = strings.TrimSpace(arg)
+		term := expiryTerm{kind: kind}
+
+		switch kind {
+		case "all":
+			if hasArg {
+				return ExpiryRule{}, fmt.Errorf("expiry rule %q takes no argument", raw)
+			}
+		case "monthly", "monthlies":
+			term.kind = "monthly"
+			if hasArg {
+				n, err := parseExpiryCount(arg)
+				if err != nil {
+					return ExpiryRule{}, fmt.Errorf("expiry rule %q: %w", raw, err)
+				}
+				term.n = n
+			}
+		case "next", "weekly", "weeklies":
+			if kind == "weeklies" {
+				term.kind = "weekly"
+			}
+			n, err := parseExpiryCount(arg)
+			if err != nil {
+				return ExpiryRule{}, fmt.Errorf("expiry rule %q: %w", raw, err)
+			}
+			term.n = n
+		case "list":
+			for _, d := range strings.Split(arg, ",") {
+				d = strings.TrimSpace(d)
+				if d == "" {
+					continue
+				}
+				parsed, err := time.Parse(expiryLayout, d)
+				if err != nil {
+					return ExpiryRule{}, fmt.Errorf("expiry rule %q: invalid date %q", raw, d)
+				}
+				term.dates = append(term.dates, parsed.Format(expiryLayout))
+			}
+			if len(term.dates) == 0 {
+				return ExpiryRule{}, fmt.Errorf("expiry rule %q: empty list", raw)
+			}
+		default:
+			return ExpiryRule{}, fmt.Errorf("unknown expiry rule %q", raw)
+		}
+
+		rule.terms = append(rule.terms, term)
+	}
+
+	if len(rule.terms) == 0 {
+		return ExpiryRule{}, fmt.Errorf("empty expiry rule")
+	}
+	return rule, nil
+}
+
+// ExpiryRuleFromEnv parses NSE_EXPIRIES, falling back to DefaultExpiryRule.
+func ExpiryRuleFromEnv() (ExpiryRule, error) {
+	spec := os.Getenv("NSE_EXPIRIES")
+	if spec == "" {
+		spec = DefaultExpiryRule
+	}
+	return ParseExpiryRule(spec)
+}
+
+func (r ExpiryRule) String() string {
+	return r.spec
+}
+
+// Select returns the expiries from the listed catalog that the rule
+// captures, in chronological order. Unparseable catalog entries are ignored.
+func (r ExpiryRule) Select(listed []string) []string {
+	type expiry struct {
+		raw string
+		at  time.Time
+	}
+
+	var catalog []expiry
+	for _, raw := range listed {
+		at, err := time.Parse(expiryLayout, raw)
+		if err != nil {
+			continue
+		}
+		catalog = append(catalog, expiry{raw: raw, at: at})
+	}
+	sort.SliceStable(catalog, func(i, j int) bool { return catalog[i].at.Before(catalog[j].at) })
+
+	isMonthly := make([]bool, len(catalog))
+	for i := range catalog {
+		last := i == len(catalog)-1
+		if !last {
+			next := catalog[i+1].at
+			last = next.Year() != catalog[i].at.Year() || next.Month() != catalog[i].at.Month()
+		}
+		isMonthly[i] = last
+	}
+
+	chosen := make([]bool, len(catalog))
+	for _, term := range r.terms {
+		taken := 0
+		for i, e := range catalog {
+			switch term.kind {
+			case "all":
+				chosen[i] = true
+			case "next":
+				if taken < term.n {
+					chosen[i] = true
+					taken++
+				}
+			case "weekly":
+				if !isMonthly[i] && taken < term.n {
+					chosen[i] = true
+					taken++
+				}
+			case "monthly":
+				if isMonthly[i] && (term.n == 0 || taken < term.n) {
+					chosen[i] = true
+					taken++
+				}
+			case "list":
+				for _, d := range term.dates {
+					if e.at.Format(expiryLayout) == d {
+						chosen[i] = true
+					}
+				}
+			}
+		}
+	}
+
+	var out []string
+	for i, e := range catalog {
+		if chosen[i] {
+			out = append(out, e.raw)
+		}
+	}
+	return out
+}
+
+func parseExpiryCount(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("count must be a positive integer, got %q", s)
+	}
+	return n, nil
+}
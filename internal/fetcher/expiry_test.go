@@ -0,0 +1,53 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpiryRuleSelect(t *testing.T) {
+	listed := []string{
+		"21-Jul-2026", "28-Jul-2026", "04-Aug-2026", "11-Aug-2026",
+		"18-Aug-2026", "25-Aug-2026", "29-Sep-2026", "29-Dec-2026",
+	}
+
+	tests := []struct {
+		rule string
+		want []string
+	}{
+		{"next:2", []string{"21-Jul-2026", "28-Jul-2026"}},
+		{"monthly", []string{"28-Jul-2026", "25-Aug-2026", "29-Sep-2026", "29-Dec-2026"}},
+		{"weekly:4+monthly:1", []string{"21-Jul-2026", "28-Jul-2026", "04-Aug-2026", "11-Aug-2026", "18-Aug-2026"}},
+		{"list:29-Sep-2026,01-Jan-2027", []string{"29-Sep-2026"}},
+		{"next:20", listed},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseExpiryRule(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseExpiryRule(%q): %v", tt.rule, err)
+		}
+		if got := rule.Select(listed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestExpiryRuleSingleListedExpiry(t *testing.T) {
+	rule, err := ParseExpiryRule(DefaultExpiryRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rule.Select([]string{"21-Jul-2026"})
+	if !reflect.DeepEqual(got, []string{"21-Jul-2026"}) {
+		t.Errorf("got %v, want the single listed expiry", got)
+	}
+}
+
+func TestParseExpiryRuleRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{"", "next", "next:0", "weekly:x", "list:", "list:2026-07-21", "soon"} {
+		if _, err := ParseExpiryRule(spec); err == nil {
+			t.Errorf("ParseExpiryRule(%q): expected error", spec)
+		}
+	}
+}
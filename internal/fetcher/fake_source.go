@@ -0,0 +1,47 @@
+package fetcher
+
+import (
+	"context"
+	"server/internal/models"
+	"server/internal/symbols"
+	"sync"
+)
+
+// FakeResponse is one canned reply from a FakeSource.
+type FakeResponse struct {
+	Chain models.OptionChain
+	Err   error
+}
+
+// FakeSource is an OptionChainSource that serves canned responses in
+// order, for tests. Once they run out the last response repeats.
+type FakeSource struct {
+	Responses []FakeResponse
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *FakeSource) FetchOptionChain(ctx context.Context, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.Responses) == 0 {
+		return models.OptionChain{}, ctx.Err()
+	}
+
+	i := f.calls
+	if i >= len(f.Responses) {
+		i = len(f.Responses) - 1
+	}
+	f.calls++
+
+	return f.Responses[i].Chain, f.Responses[i].Err
+}
+
+// Calls returns how many times FetchOptionChain has been called.
+func (f *FakeSource) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
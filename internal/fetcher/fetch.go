@@ -2,22 +2,44 @@ package fetcher
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"server/internal/market"
 	"server/internal/models"
+	"server/internal/symbols"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 type FetcherService struct {
-	Writer  Writer
-	Browser *Browser
+	Symbols []symbols.Symbol
+	// Writers holds the stream writer for each configured symbol, keyed by
+	// Symbol.Name.
+	Writers map[string]Writer
+	Source  OptionChainSource
+	// Expiries selects which listed expiries are captured per snapshot.
+	// The zero value falls back to DefaultExpiryRule.
+	Expiries ExpiryRule
+	Schedule *market.Schedule
+	// RetryDelay is the pause between failed or stale fetch attempts
+	// within a tick. Zero means defaultRetryDelay.
+	RetryDelay time.Duration
 }
 
 const (
 	preOpenPollInterval = 1 * time.Second
 	marketPollInterval  = 3 * time.Minute
+
+	maxFetchRetries   = 10
+	defaultRetryDelay = 2 * time.Second
 )
 
+// FetchData polls every configured symbol on its own schedule until ctx is
+// cancelled. Each symbol runs in its own goroutine so a slow or failing
+// symbol doesn't hold up the others.
 func (fs *FetcherService) FetchData(ctx context.Context, logger *slog.Logger) error {
 	loc, err := time.LoadLocation("Asia/Kolkata")
 	if err != nil {
@@ -25,8 +47,43 @@ func (fs *FetcherService) FetchData(ctx context.Context, logger *slog.Logger) er
 		return err
 	}
 
-	currentInterval := marketPollInterval
-	if now := time.Now().In(loc); shouldPollFast(now, loc) {
+	if len(fs.Expiries.terms) == 0 {
+		fs.Expiries, err = ParseExpiryRule(DefaultExpiryRule)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sym := range fs.Symbols {
+		if fs.Writers[sym.Name] == nil {
+			return fmt.Errorf("no writer configured for symbol %s", sym.Name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, sym := range fs.Symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fs.fetchSymbol(ctx, logger.With(slog.String("symbol", sym.Name)), sym, loc)
+		}()
+	}
+	wg.Wait()
+
+	logger.Info("Gracefully shutdown through context cancellation")
+	return nil
+}
+
+func (fs *FetcherService) fetchSymbol(ctx context.Context, logger *slog.Logger, sym symbols.Symbol, loc *time.Location) {
+	writer := fs.Writers[sym.Name]
+
+	pollInterval := marketPollInterval
+	if sym.PollInterval > 0 {
+		pollInterval = sym.PollInterval
+	}
+
+	currentInterval := pollInterval
+	if state := fs.Schedule.At(time.Now()); shouldPollFast(state) {
 		currentInterval = preOpenPollInterval
 	}
 
@@ -36,19 +93,14 @@ func (fs *FetcherService) FetchData(ctx context.Context, logger *slog.Logger) er
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Info("Gracefully shutdown through context cancellation")
-			return nil
+			return
 
 		case <-ticker.C:
 			now := time.Now().In(loc)
+			state := fs.Schedule.At(now)
 
-			startTime := time.Date(now.Year(), now.Month(), now.Day(), 9, 15, 0, 0, loc)
-			endTime := time.Date(now.Year(), now.Month(), now.Day(), 15, 30, 0, 0, loc)
-			resetTime := time.Date(now.Year(), now.Month(), now.Day(), 23, 0, 0, 0, loc)
-			currentDate := now.Format("02-Jan-2006")
-
-			desiredInterval := marketPollInterval
-			if shouldPollFast(now, loc) {
+			desiredInterval := pollInterval
+			if shouldPollFast(state) {
 				desiredInterval = preOpenPollInterval
 			}
 			if desiredInterval != currentInterval {
@@ -56,120 +108,151 @@ func (fs *FetcherService) FetchData(ctx context.Context, logger *slog.Logger) er
 				currentInterval = desiredInterval
 			}
 
-			// Market closed today
-			if isMarketHoliday(now, loc) {
-				logger.Info("Market is closed today. Skipping data fetch.")
-				continue
-			}
-
-			// Before market open
-			if now.Before(startTime) {
-				logger.Debug("Market not started yet. Waiting for market to open.")
-				continue
-			}
+			switch state.Phase {
+			case market.Normal:
+				// Fetch below.
 
-			// After reset time → cleanup & wait for tomorrow
-			if now.After(resetTime) {
-				if err := fs.Writer.Delete(ctx); err != nil {
+			case market.Reset:
+				// Cleanup & wait for tomorrow
+				if err := writer.Delete(ctx); err != nil {
 					logger.Error("Failed to reset stream", slog.String("err", err.Error()))
 				} else {
 					logger.Info("Reset stream for new trading day.")
 				}
 				continue
-			}
 
-			// After market close → stop until reset
-			if now.After(endTime) {
+			case market.PostClose:
 				logger.Info("Market closed. Stopping data fetch until reset.")
 				continue
-			}
 
-			// ---- Fetching starts here ----
-			var chain models.OptionChain
-			success := false
-			maxRetries := 10
-
-			for i := 0; i < maxRetries; i++ {
-				var optionChainError error
-				chain, optionChainError = fs.Browser.getOptionChain(ctx)
-				if optionChainError != nil {
-					logger.Error("Failed to fetch option chain",
-						slog.Int("attempt", i+1),
-						slog.String("err", optionChainError.Error()))
-					time.Sleep(2 * time.Second)
-					continue
-				}
-
-				if chain.Records.TimeStamp == "" || !strings.Contains(chain.Records.TimeStamp, currentDate) {
-					logger.Warn("Fetched stale data, retrying...",
-						slog.Int("attempt", i+1))
-					time.Sleep(2 * time.Second)
-					continue
+			default:
+				if !state.TradingDay {
+					logger.Info("Market is closed today. Skipping data fetch.",
+						slog.String("reason", fs.Schedule.Calendar().Describe(now)))
+				} else {
+					logger.Debug("Market not started yet. Waiting for market to open.",
+						slog.String("phase", string(state.Phase)))
 				}
-
-				success = true
-				break
-			}
-
-			if !success {
-				logger.Error("All retries failed — skipping this tick")
 				continue
 			}
 
-			if len(chain.Records.ExpiryDates) < 2 {
-				logger.Error("Not enough expiry dates")
-				continue
+			if err := fs.fetchSnapshot(ctx, logger, sym, writer, now); err != nil {
+				logger.Error(err.Error())
 			}
+		}
+	}
+}
 
-			firstExpiry := chain.Records.ExpiryDates[0]
-			secondExpiry := chain.Records.ExpiryDates[1]
+// fetchSnapshot fetches one snapshot of sym, retrying failed and stale
+// fetches, and writes the selected expiries' rows to writer. now is the
+// tick's time and decides which trading date counts as fresh.
+func (fs *FetcherService) fetchSnapshot(ctx context.Context, logger *slog.Logger, sym symbols.Symbol, writer Writer, now time.Time) error {
+	currentDate := now.Format("02-Jan-2006")
+	retryDelay := fs.RetryDelay
+	if retryDelay == 0 {
+		retryDelay = defaultRetryDelay
+	}
 
-			var expiryOneResult, expiryTwoResult []models.OptionData
-			for _, entry := range chain.Records.Data {
-				data := models.OptionData{
-					StrikePrice: entry.StrikePrice,
-					CE:          entry.CE,
-					PE:          entry.PE,
-					ExpiryDate:  entry.ExpiryDate,
-				}
+	var chain models.OptionChain
+	success := false
 
-				switch entry.ExpiryDate {
-				case firstExpiry:
-					expiryOneResult = append(expiryOneResult, data)
-				case secondExpiry:
-					expiryTwoResult = append(expiryTwoResult, data)
-				}
+	for i := 0; i < maxFetchRetries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
 			}
+		}
 
-			logger.Info("Fetched data",
-				slog.Int("records_first_expiry", len(expiryOneResult)),
-				slog.Int("records_second_expiry", len(expiryTwoResult)),
-			)
-
-			err = fs.Writer.Write(ctx, models.Records{
-				ExpiryDates:     chain.Records.ExpiryDates,
-				Data:            append(expiryOneResult, expiryTwoResult...),
-				TimeStamp:       chain.Records.TimeStamp,
-				UnderlyingValue: chain.Records.UnderlyingValue,
-			})
-			if err != nil {
-				logger.Error("Failed to write to stream", slog.String("err", err.Error()))
-				continue
-			}
+		var optionChainError error
+		chain, optionChainError = fs.Source.FetchOptionChain(ctx, sym, fs.Expiries)
+		if optionChainError != nil {
+			logger.Error("Failed to fetch option chain",
+				slog.Int("attempt", i+1),
+				slog.String("err", optionChainError.Error()))
+			continue
+		}
+
+		if chain.Records.TimeStamp == "" || !strings.Contains(chain.Records.TimeStamp, currentDate) {
+			logger.Warn("Fetched stale data, retrying...",
+				slog.Int("attempt", i+1))
+			continue
+		}
+
+		success = true
+		break
+	}
+
+	if !success {
+		return errors.New("all retries failed — skipping this tick")
+	}
 
-			logger.Info("Successfully wrote data to stream")
+	selected := fs.Expiries.Select(chain.Records.ExpiryDates)
+	if len(selected) == 0 {
+		return fmt.Errorf("no listed expiry matches rule %q (listed: %v)", fs.Expiries, chain.Records.ExpiryDates)
+	}
+
+	data, perExpiry := splitByExpiry(chain.Records.Data, selected)
+
+	logger.Info("Fetched data",
+		slog.Any("records_per_expiry", perExpiry),
+	)
+
+	err := writer.Write(ctx, models.Records{
+		ExpiryDates:     chain.Records.ExpiryDates,
+		Data:            data,
+		TimeStamp:       chain.Records.TimeStamp,
+		UnderlyingValue: chain.Records.UnderlyingValue,
+		StrikePrices:    chain.Records.StrikePrices,
+		Totals:          selectedTotals(chain.Records.Totals, selected),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write to stream: %w", err)
+	}
+
+	logger.Info("Successfully wrote data to stream")
+	return nil
+}
+
+// splitByExpiry keeps the rows belonging to the selected expiries, grouped
+// in the order the expiries are listed, and reports how many rows each
+// expiry contributed.
+func splitByExpiry(rows []models.OptionData, selected []string) ([]models.OptionData, map[string]int) {
+	byExpiry := make(map[string][]models.OptionData, len(selected))
+	for _, entry := range rows {
+		byExpiry[entry.ExpiryDate] = append(byExpiry[entry.ExpiryDate], models.OptionData{
+			StrikePrice: entry.StrikePrice,
+			CE:          entry.CE,
+			PE:          entry.PE,
+			ExpiryDate:  entry.ExpiryDate,
+		})
+	}
+
+	var data []models.OptionData
+	counts := make(map[string]int, len(selected))
+	for _, expiry := range selected {
+		data = append(data, byExpiry[expiry]...)
+		counts[expiry] = len(byExpiry[expiry])
+	}
+	return data, counts
+}
+
+// selectedTotals keeps the per-expiry totals of the selected expiries.
+func selectedTotals(totals []models.ChainTotals, selected []string) []models.ChainTotals {
+	var out []models.ChainTotals
+	for _, t := range totals {
+		if slices.Contains(selected, t.ExpiryDate) {
+			out = append(out, t)
 		}
 	}
+	return out
 }
 
 // shouldPollFast reports whether the fetcher should poll every second
 // rather than every 3 minutes: only on trading days, before market open,
-// so we catch the 9:15 open promptly without spinning all day on
+// so we catch the open promptly without spinning all day on
 // weekends/holidays or during the rest of the trading day.
-func shouldPollFast(now time.Time, loc *time.Location) bool {
-	if isMarketHoliday(now, loc) {
-		return false
-	}
-	startTime := time.Date(now.Year(), now.Month(), now.Day(), 9, 15, 0, 0, loc)
-	return now.Before(startTime)
+func shouldPollFast(state market.State) bool {
+	return state.BeforeOpen()
 }
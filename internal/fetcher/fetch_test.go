@@ -0,0 +1,211 @@
+package fetcher
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"server/internal/models"
+	"server/internal/symbols"
+	"testing"
+	"time"
+)
+
+var testSymbol = symbols.Symbol{Name: "NIFTY", Kind: symbols.Indices}
+
+type recordingWriter struct {
+	writes []models.Records
+}
+
+func (w *recordingWriter) Write(ctx context.Context, data models.Records) error {
+	w.writes = append(w.writes, data)
+	return nil
+}
+
+func (w *recordingWriter) Delete(ctx context.Context) error {
+	w.writes = nil
+	return nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testChain(timestamp string, expiries ...string) models.OptionChain {
+	var data []models.OptionData
+	for _, e := range expiries {
+		for _, strike := range []float64{24200, 24250} {
+			data = append(data, models.OptionData{
+				StrikePrice: strike,
+				ExpiryDate:  e,
+				CE:          &models.Option{StrikePrice: strike, ExpiryDate: e},
+			})
+		}
+	}
+	return models.OptionChain{Records: models.Records{
+		ExpiryDates:     expiries,
+		Data:            data,
+		TimeStamp:       timestamp,
+		UnderlyingValue: 24268.85,
+	}}
+}
+
+func newTestService(t *testing.T, source OptionChainSource, rule string) *FetcherService {
+	t.Helper()
+	r, err := ParseExpiryRule(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &FetcherService{
+		Symbols:    []symbols.Symbol{testSymbol},
+		Source:     source,
+		Expiries:   r,
+		RetryDelay: time.Millisecond,
+	}
+}
+
+var tickTime = time.Date(2026, time.July, 17, 11, 45, 0, 0, time.UTC)
+
+func TestFetchSnapshotRetriesErrors(t *testing.T) {
+	source := &FakeSource{Responses: []FakeResponse{
+		{Err: errors.New("boom")},
+		{Err: errors.New("boom again")},
+		{Chain: testChain("17-Jul-2026 11:43:19", "21-Jul-2026", "28-Jul-2026")},
+	}}
+	w := &recordingWriter{}
+	fs := newTestService(t, source, "next:2")
+
+	if err := fs.fetchSnapshot(context.Background(), discardLogger(), testSymbol, w, tickTime); err != nil {
+		t.Fatalf("fetchSnapshot: %v", err)
+	}
+	if source.Calls() != 3 {
+		t.Errorf("got %d calls, want 3", source.Calls())
+	}
+	if len(w.writes) != 1 || len(w.writes[0].Data) != 4 {
+		t.Fatalf("unexpected writes %+v", w.writes)
+	}
+}
+
+func TestFetchSnapshotStaleDataSkipsTick(t *testing.T) {
+	source := &FakeSource{Responses: []FakeResponse{
+		{Chain: testChain("16-Jul-2026 15:30:00", "21-Jul-2026")},
+	}}
+	w := &recordingWriter{}
+	fs := newTestService(t, source, "next:2")
+
+	if err := fs.fetchSnapshot(context.Background(), discardLogger(), testSymbol, w, tickTime); err == nil {
+		t.Fatal("expected stale data to fail the tick")
+	}
+	if source.Calls() != maxFetchRetries {
+		t.Errorf("got %d calls, want %d", source.Calls(), maxFetchRetries)
+	}
+	if len(w.writes) != 0 {
+		t.Errorf("stale data was written: %+v", w.writes)
+	}
+}
+
+func TestFetchSnapshotSplitsSelectedExpiries(t *testing.T) {
+	source := &FakeSource{Responses: []FakeResponse{
+		{Chain: testChain("17-Jul-2026 11:43:19", "21-Jul-2026", "28-Jul-2026", "04-Aug-2026")},
+	}}
+	w := &recordingWriter{}
+	fs := newTestService(t, source, "next:1+list:04-Aug-2026")
+
+	if err := fs.fetchSnapshot(context.Background(), discardLogger(), testSymbol, w, tickTime); err != nil {
+		t.Fatalf("fetchSnapshot: %v", err)
+	}
+	if len(w.writes) != 1 {
+		t.Fatalf("got %d writes, want 1", len(w.writes))
+	}
+
+	var got []string
+	for _, row := range w.writes[0].Data {
+		got = append(got, row.ExpiryDate)
+	}
+	want := []string{"21-Jul-2026", "21-Jul-2026", "04-Aug-2026", "04-Aug-2026"}
+	if len(got) != len(want) {
+		t.Fatalf("got expiries %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got expiries %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFileSourceServesCaptures(t *testing.T) {
+	fs := newTestService(t, NewFileSource("testdata"), "next:1")
+	w := &recordingWriter{}
+
+	if err := fs.fetchSnapshot(context.Background(), discardLogger(), testSymbol, w, tickTime); err != nil {
+		t.Fatalf("fetchSnapshot: %v", err)
+	}
+	if len(w.writes) != 1 {
+		t.Fatalf("got %d writes, want 1", len(w.writes))
+	}
+	rec := w.writes[0]
+	if rec.TimeStamp != "17-Jul-2026 11:43:19" || rec.UnderlyingValue != 24268.85 || len(rec.Data) != 8 {
+		t.Errorf("unexpected snapshot: ts=%q underlying=%v rows=%d", rec.TimeStamp, rec.UnderlyingValue, len(rec.Data))
+	}
+
+	ce := rec.Data[0].CE
+	if ce == nil || ce.BidPrice != 205.4 || ce.BidQty != 195 || ce.AskPrice != 205.45 || ce.AskQty != 65 {
+		t.Errorf("top of book not decoded from buy/sell fields: %+v", ce)
+	}
+
+	if len(rec.StrikePrices) != 8 || rec.StrikePrices[0] != 24100 {
+		t.Errorf("strike prices not decoded: %v", rec.StrikePrices)
+	}
+	if len(rec.Totals) != 1 || rec.Totals[0].ExpiryDate != "21-Jul-2026" ||
+		rec.Totals[0].CETotalOI != 2376947 || rec.Totals[0].PETotalVolume != 16146868 {
+		t.Errorf("filtered totals not captured: %+v", rec.Totals)
+	}
+}
+
+func TestFileSourceServesGzippedCaptures(t *testing.T) {
+	dir := t.TempDir()
+	symDir := filepath.Join(dir, testSymbol.Slug())
+	if err := os.MkdirAll(symDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"20260717T114319.json", "20260717T114619.json"} {
+		raw, err := os.ReadFile(filepath.Join("testdata", testSymbol.Slug(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := os.Create(filepath.Join(symDir, name+".gz"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := gzip.NewWriter(out)
+		if _, err := zw.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := NewFileSource(dir)
+	var got []string
+	for range 3 {
+		chain, err := src.FetchOptionChain(context.Background(), testSymbol, ExpiryRule{})
+		if err != nil {
+			t.Fatalf("FetchOptionChain: %v", err)
+		}
+		got = append(got, chain.Records.TimeStamp)
+	}
+
+	want := []string{"17-Jul-2026 11:43:19", "17-Jul-2026 11:46:19", "17-Jul-2026 11:46:19"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("served %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"server/internal/models"
+	"server/internal/symbols"
+	"sync"
+)
+
+// FileSource serves captured option-chain-v3 responses from disk, one
+// capture per call, so the fetcher can run without NSE access. It reads
+// the same layouts as ReplayOptions.Dir: <dir>/<slug>.jsonl, raw or
+// gzipped files under <dir>/<slug>/ (e.g. testdata/nifty50/), or archived
+// snapshots. Files are served in name order, so timestamped names replay
+// chronologically. Once they run out, the last capture keeps being served.
+type FileSource struct {
+	dir string
+
+	mu       sync.Mutex
+	captures map[string][]models.OptionChain
+	next     map[string]int
+}
+
+func NewFileSource(dir string) *FileSource {
+	return &FileSource{
+		dir:      dir,
+		captures: make(map[string][]models.OptionChain),
+		next:     make(map[string]int),
+	}
+}
+
+func (f *FileSource) FetchOptionChain(ctx context.Context, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error) {
+	if err := ctx.Err(); err != nil {
+		return models.OptionChain{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	chains, ok := f.captures[sym.Name]
+	if !ok {
+		var err error
+		chains, err = loadCaptureChains(f.dir, sym)
+		if err != nil {
+			return models.OptionChain{}, err
+		}
+		if len(chains) == 0 {
+			return models.OptionChain{}, fmt.Errorf("no captures for %s in %s", sym.Name, f.dir)
+		}
+		f.captures[sym.Name] = chains
+	}
+
+	i := f.next[sym.Name]
+	if i >= len(chains) {
+		i = len(chains) - 1
+	}
+	f.next[sym.Name] = i + 1
+
+	return filterExpiries(chains[i], rule), nil
+}
+
+// filterExpiries drops rows whose expiry isn't selected by rule, leaving
+// the rest of the chain untouched.
+func filterExpiries(chain models.OptionChain, rule ExpiryRule) models.OptionChain {
+	selected := make(map[string]struct{})
+	for _, e := range rule.Select(chain.Records.ExpiryDates) {
+		selected[e] = struct{}{}
+	}
+
+	var data []models.OptionData
+	for _, row := range chain.Records.Data {
+		if _, ok := selected[row.ExpiryDate]; ok {
+			data = append(data, row)
+		}
+	}
+	chain.Records.Data = data
+	return chain
+}
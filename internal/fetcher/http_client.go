@@ -0,0 +1,170 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"server/internal/models"
+	"server/internal/symbols"
+	"strings"
+	"sync"
+	"time"
+)
+
+const httpRequestTimeout = 15 * time.Second
+
+// errSessionRejected marks responses that mean NSE didn't accept the
+// current cookies - a 401/403 or an HTML bot-check page instead of JSON -
+// so the session should be primed again.
+var errSessionRejected = errors.New("nse rejected session")
+
+// HTTPClient fetches option chains with plain net/http requests, the same
+// way fetch_nse.sh does with curl: it loads the option-chain page once to
+// collect NSE's session cookies into a jar, then calls the JSON API with
+// the Referer and User-Agent the site's own frontend sends. It needs no
+// Chromium, so it suits lightweight deployments, at the cost of being
+// easier for NSE's bot detection to turn away than the real browser.
+type HTTPClient struct {
+	// Archiver, if set, receives every raw response fetched.
+	Archiver *Archiver
+
+	// baseURL is the site the page and API are fetched from, NSE's own
+	// outside tests.
+	baseURL string
+	client  *http.Client
+
+	mu       sync.Mutex
+	primedAt time.Time
+}
+
+// NewHTTPClient returns an HTTPClient with an empty cookie jar; cookies
+// are primed lazily on the first fetch.
+func NewHTTPClient() *HTTPClient {
+	// cookiejar.New only fails when given a PublicSuffixList that errors.
+	jar, _ := cookiejar.New(nil)
+	return &HTTPClient{
+		baseURL: nseBaseURL,
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: httpRequestTimeout,
+		},
+	}
+}
+
+// Close releases idle connections. Safe to call on shutdown alongside
+// Browser.Close.
+func (c *HTTPClient) Close() {
+	c.client.CloseIdleConnections()
+}
+
+func (c *HTTPClient) FetchOptionChain(ctx context.Context, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error) {
+	if err := c.prime(ctx, false); err != nil {
+		return models.OptionChain{}, fmt.Errorf("cookie setup failed: %w", err)
+	}
+	return fetchOptionChain(ctx, c.fetchJSON, c.baseURL, c.Archiver, sym, rule)
+}
+
+// prime loads the option-chain page so NSE sets its session cookies in the
+// jar. force bypasses the TTL, used after NSE rejects the current session.
+func (c *HTTPClient) prime(ctx context.Context, force bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !force && time.Since(c.primedAt) < cookieRefreshTTL {
+		return nil
+	}
+
+	page := c.page()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", page, err)
+	}
+	defer resp.Body.Close()
+	// Drain so the connection can be reused; only the cookies matter.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to load %s: HTTP %d", page, resp.StatusCode)
+	}
+
+	c.primedAt = time.Now()
+	return nil
+}
+
+// page is the option-chain page that hands out the session cookies.
+func (c *HTTPClient) page() string {
+	return c.baseURL + "/option-chain"
+}
+
+// fetchJSON calls an NSE API url with the primed cookies and unmarshals the
+// JSON body into out, returning the raw body. If NSE turns the session
+// away it primes a fresh one and tries once more before giving up.
+func (c *HTTPClient) fetchJSON(ctx context.Context, url string, out any) ([]byte, error) {
+	body, err := c.doJSON(ctx, url, out)
+	if !errors.Is(err, errSessionRejected) {
+		return body, err
+	}
+
+	if primeErr := c.prime(ctx, true); primeErr != nil {
+		return nil, fmt.Errorf("%w (re-prime failed: %v)", err, primeErr)
+	}
+	return c.doJSON(ctx, url, out)
+}
+
+func (c *HTTPClient) doJSON(ctx context.Context, url string, out any) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Referer", c.page())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w: HTTP %d from %s", errSessionRejected, resp.StatusCode, url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty response body from %s", url)
+	}
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") || strings.HasPrefix(trimmed, "<") {
+		return nil, fmt.Errorf("%w: HTML instead of JSON from %s", errSessionRejected, url)
+	}
+
+	if err := json.Unmarshal([]byte(trimmed), out); err != nil {
+		preview := trimmed
+		if len(preview) > 200 {
+			preview = preview[:200]
+		}
+		return nil, fmt.Errorf("json unmarshal failed for %s: %w | body preview: %s", url, err, preview)
+	}
+	return []byte(trimmed), nil
+}
@@ -0,0 +1,142 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeNSE serves the option-chain page, which hands out a session cookie,
+// and the two API endpoints, which refuse requests without it. The first
+// `rejections` API requests are answered by reject instead.
+type fakeNSE struct {
+	chain      []byte
+	rejections int
+	reject     http.HandlerFunc
+
+	primes atomic.Int32
+	calls  atomic.Int32
+}
+
+func (f *fakeNSE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/option-chain":
+		f.primes.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "nsit", Value: "session", Path: "/"})
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html></html>"))
+		return
+	case "/api/option-chain-contract-info", "/api/option-chain-v3":
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := r.Cookie("nsit"); err != nil {
+		http.Error(w, "no session", http.StatusUnauthorized)
+		return
+	}
+	if n := f.calls.Add(1); int(n) <= f.rejections {
+		f.reject(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/api/option-chain-contract-info" {
+		_, _ = w.Write([]byte(`{"expiryDates": ["21-Jul-2026", "28-Jul-2026"]}`))
+		return
+	}
+	_, _ = w.Write(f.chain)
+}
+
+func newFakeNSE(t *testing.T, rejections int, reject http.HandlerFunc) (*fakeNSE, *HTTPClient) {
+	t.Helper()
+	chain, err := os.ReadFile(filepath.Join("testdata", testSymbol.Slug(), "20260717T114319.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nse := &fakeNSE{chain: chain, rejections: rejections, reject: reject}
+	srv := httptest.NewServer(nse)
+	t.Cleanup(srv.Close)
+
+	client := NewHTTPClient()
+	client.baseURL = srv.URL
+	t.Cleanup(client.Close)
+	return nse, client
+}
+
+func nextExpiry(t *testing.T) ExpiryRule {
+	t.Helper()
+	rule, err := ParseExpiryRule("next:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func TestHTTPClientFetchesWithPrimedSession(t *testing.T) {
+	nse, client := newFakeNSE(t, 0, nil)
+
+	chain, err := client.FetchOptionChain(context.Background(), testSymbol, nextExpiry(t))
+	if err != nil {
+		t.Fatalf("FetchOptionChain: %v", err)
+	}
+	if chain.Records.TimeStamp != "17-Jul-2026 11:43:19" || len(chain.Records.Data) == 0 {
+		t.Errorf("unexpected chain: ts=%q rows=%d", chain.Records.TimeStamp, len(chain.Records.Data))
+	}
+	if got := nse.primes.Load(); got != 1 {
+		t.Errorf("primed %d times, want 1", got)
+	}
+
+	if _, err := client.FetchOptionChain(context.Background(), testSymbol, nextExpiry(t)); err != nil {
+		t.Fatalf("second FetchOptionChain: %v", err)
+	}
+	if got := nse.primes.Load(); got != 1 {
+		t.Errorf("primed %d times within the cookie TTL, want 1", got)
+	}
+}
+
+func TestHTTPClientRetriesRejectedSession(t *testing.T) {
+	rejections := map[string]http.HandlerFunc{
+		"401": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		},
+		"403": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		},
+		"html": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html>Access Denied</html>"))
+		},
+	}
+
+	for name, reject := range rejections {
+		t.Run(name, func(t *testing.T) {
+			nse, client := newFakeNSE(t, 1, reject)
+
+			if _, err := client.FetchOptionChain(context.Background(), testSymbol, nextExpiry(t)); err != nil {
+				t.Fatalf("FetchOptionChain: %v", err)
+			}
+			if got := nse.primes.Load(); got != 2 {
+				t.Errorf("primed %d times, want 2 (initial and one re-prime)", got)
+			}
+		})
+
+		t.Run(name+" twice", func(t *testing.T) {
+			nse, client := newFakeNSE(t, 2, reject)
+
+			_, err := client.FetchOptionChain(context.Background(), testSymbol, nextExpiry(t))
+			if !errors.Is(err, errSessionRejected) {
+				t.Fatalf("got error %v, want errSessionRejected", err)
+			}
+			if got := nse.primes.Load(); got != 2 {
+				t.Errorf("primed %d times, want 2 (no second re-prime)", got)
+			}
+		})
+	}
+}
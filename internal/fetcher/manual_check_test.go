@@ -2,6 +2,7 @@ package fetcher
 
 import (
 	"context"
+	"server/internal/symbols"
 	"testing"
 	"time"
 )
@@ -13,9 +14,14 @@ func TestManualFetchOptionChain(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	chain, err := b.getOptionChain(ctx)
+	rule, err := ParseExpiryRule(DefaultExpiryRule)
 	if err != nil {
-		t.Fatalf("getOptionChain error: %v", err)
+		t.Fatalf("ParseExpiryRule error: %v", err)
+	}
+
+	chain, err := b.FetchOptionChain(ctx, symbols.Symbol{Name: "NIFTY", Kind: symbols.Indices}, rule)
+	if err != nil {
+		t.Fatalf("FetchOptionChain error: %v", err)
 	}
 
 	t.Logf("timestamp=%q underlying=%v expiries=%v records=%d",
@@ -0,0 +1,366 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"server/internal/models"
+	"server/internal/symbols"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	timestampLayout    = "02-Jan-2006 15:04:05"
+	contractDateLayout = "02-01-2006"
+
+	// maxCaptureLineSize bounds one JSONL line; a full option-chain-v3
+	// response for a single expiry is around 400KB.
+	maxCaptureLineSize = 16 << 20
+)
+
+// Capture is one recorded option chain snapshot.
+type Capture struct {
+	At    time.Time
+	Chain models.OptionChain
+}
+
+// ReplayOptions configures FetcherService.ReplayData.
+type ReplayOptions struct {
+	// Dir holds each symbol's captures: a JSONL file <Dir>/<slug>.jsonl
+	// with one response per line, a directory of raw NSE JSON files
+	// <Dir>/<slug>/*.json, or the Archiver's snapshot layout, so pointing
+	// Dir at an archive's raw/ directory replays archived history.
+	Dir string
+	// Speed scales the gaps between captures: 1 replays in real time, 10
+	// ten times faster, and 0 writes them back to back.
+	Speed float64
+	// Rebase moves each capture onto today's date, keeping its time of day
+	// and shifting expiries by the same number of days, so the processor
+	// accepts it as today's data.
+	Rebase bool
+}
+
+// ReplayData feeds recorded captures for every configured symbol through
+// the normal Writers in place of live fetching, so the pipeline from
+// Redis through the processor to SSE can run offline. It returns once all
+// captures are written or ctx is cancelled.
+func (fs *FetcherService) ReplayData(ctx context.Context, logger *slog.Logger, opts ReplayOptions) error {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		return err
+	}
+
+	if len(fs.Expiries.terms) == 0 {
+		fs.Expiries, err = ParseExpiryRule(DefaultExpiryRule)
+		if err != nil {
+			return err
+		}
+	}
+
+	captures := make(map[string][]Capture, len(fs.Symbols))
+	for _, sym := range fs.Symbols {
+		if fs.Writers[sym.Name] == nil {
+			return fmt.Errorf("no writer configured for symbol %s", sym.Name)
+		}
+		c, err := LoadCaptures(opts.Dir, sym, loc)
+		if err != nil {
+			return fmt.Errorf("symbol %s: %w", sym.Name, err)
+		}
+		captures[sym.Name] = c
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(fs.Symbols))
+	for i, sym := range fs.Symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			symLogger := logger.With(slog.String("symbol", sym.Name))
+			errs[i] = fs.replaySymbol(ctx, symLogger, fs.Writers[sym.Name], captures[sym.Name], opts, loc)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (fs *FetcherService) replaySymbol(ctx context.Context, logger *slog.Logger, writer Writer, captures []Capture, opts ReplayOptions, loc *time.Location) error {
+	logger.Info("Replaying captures", slog.Int("count", len(captures)), slog.Float64("speed", opts.Speed))
+
+	for i, capture := range captures {
+		if i > 0 && opts.Speed > 0 {
+			gap := time.Duration(float64(capture.At.Sub(captures[i-1].At)) / opts.Speed)
+			if gap > 0 {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(gap):
+				}
+			}
+		}
+
+		chain := capture.Chain
+		if opts.Rebase {
+			chain = rebaseChain(chain, capture.At, time.Now().In(loc))
+		}
+
+		selected := fs.Expiries.Select(chain.Records.ExpiryDates)
+		data, perExpiry := splitByExpiry(chain.Records.Data, selected)
+
+		err := writer.Write(ctx, models.Records{
+			ExpiryDates:     chain.Records.ExpiryDates,
+			Data:            data,
+			TimeStamp:       chain.Records.TimeStamp,
+			UnderlyingValue: chain.Records.UnderlyingValue,
+			StrikePrices:    chain.Records.StrikePrices,
+			Totals:          selectedTotals(chain.Records.Totals, selected),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write capture %s: %w", capture.At.Format(timestampLayout), err)
+		}
+
+		logger.Info("Replayed capture",
+			slog.String("captured_at", capture.At.Format(timestampLayout)),
+			slog.String("timestamp", chain.Records.TimeStamp),
+			slog.Any("records_per_expiry", perExpiry))
+	}
+
+	logger.Info("Replay finished")
+	return nil
+}
+
+// LoadCaptures reads sym's captures from dir, trying <slug>.jsonl, then
+// <slug>/*.json, then archived snapshots under <slug>/<date>/<time>/, and
+// returns them in capture order. Files may be gzip-compressed.
+func LoadCaptures(dir string, sym symbols.Symbol, loc *time.Location) ([]Capture, error) {
+	chains, err := loadCaptureChains(dir, sym)
+	if err != nil {
+		return nil, err
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("no captures found for %s in %s", sym.Name, dir)
+	}
+
+	captures := make([]Capture, 0, len(chains))
+	for _, chain := range chains {
+		at, err := time.ParseInLocation(timestampLayout, chain.Records.TimeStamp, loc)
+		if err != nil {
+			return nil, fmt.Errorf("capture has invalid timestamp %q", chain.Records.TimeStamp)
+		}
+		captures = append(captures, Capture{At: at, Chain: chain})
+	}
+	sort.SliceStable(captures, func(i, j int) bool { return captures[i].At.Before(captures[j].At) })
+
+	return captures, nil
+}
+
+func loadCaptureChains(dir string, sym symbols.Symbol) ([]models.OptionChain, error) {
+	jsonl := filepath.Join(dir, sym.Slug()+".jsonl")
+	if _, err := os.Stat(jsonl); err == nil {
+		return readJSONLCaptures(jsonl)
+	}
+
+	var files []string
+	for _, pattern := range []string{"*.json", "*.json.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, sym.Slug(), pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	if len(files) > 0 {
+		var chains []models.OptionChain
+		for _, file := range files {
+			chain, err := readCaptureFile(file)
+			if err != nil {
+				return nil, err
+			}
+			chains = append(chains, chain)
+		}
+		return chains, nil
+	}
+
+	return readArchivedSnapshots(filepath.Join(dir, sym.Slug()))
+}
+
+// readArchivedSnapshots merges the per-expiry option-chain responses of
+// each archived snapshot directory into one chain per snapshot.
+func readArchivedSnapshots(symDir string) ([]models.OptionChain, error) {
+	files, err := filepath.Glob(filepath.Join(symDir, "*", "*", optionChainArchiveName("*")+archiveExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var chains []models.OptionChain
+	var current string
+	for _, file := range files {
+		part, err := readCaptureFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if snapshot := filepath.Dir(file); snapshot != current {
+			current = snapshot
+			chains = append(chains, part)
+			continue
+		}
+
+		merged := &chains[len(chains)-1]
+		merged.Records.Data = append(merged.Records.Data, part.Records.Data...)
+		merged.Records.Totals = append(merged.Records.Totals, part.Records.Totals...)
+		merged.Records.StrikePrices = mergeStrikes(merged.Records.StrikePrices, part.Records.StrikePrices)
+		merged.Records.TimeStamp = part.Records.TimeStamp
+		merged.Records.UnderlyingValue = part.Records.UnderlyingValue
+	}
+	return chains, nil
+}
+
+func readCaptureFile(path string) (models.OptionChain, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return models.OptionChain{}, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return models.OptionChain{}, fmt.Errorf("capture %s: %w", path, err)
+		}
+		raw, err = io.ReadAll(zr)
+		if err != nil {
+			return models.OptionChain{}, fmt.Errorf("capture %s: %w", path, err)
+		}
+	}
+
+	chain, err := decodeCapture(raw)
+	if err != nil {
+		return models.OptionChain{}, fmt.Errorf("capture %s: %w", path, err)
+	}
+	return chain, nil
+}
+
+func readJSONLCaptures(path string) ([]models.OptionChain, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var chains []models.OptionChain
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxCaptureLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		chain, err := decodeCapture(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		chains = append(chains, chain)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return chains, nil
+}
+
+// decodeCapture accepts either a raw NSE option-chain-v3 response or a
+// bare models.Records, as found in the Redis stream.
+func decodeCapture(raw []byte) (models.OptionChain, error) {
+	var chain models.OptionChain
+	if err := json.Unmarshal(raw, &chain); err != nil {
+		return models.OptionChain{}, err
+	}
+	if chain.Records.TimeStamp != "" {
+		attachTotals(&chain)
+		return chain, nil
+	}
+
+	var records models.Records
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return models.OptionChain{}, err
+	}
+	if records.TimeStamp == "" {
+		return models.OptionChain{}, errors.New("no timestamp found")
+	}
+	return models.OptionChain{Records: records}, nil
+}
+
+// attachTotals turns a raw single-expiry capture's "filtered" block into
+// Records.Totals, as the live sources do. Captures whose rows span several
+// expiries can't be attributed and are left without totals.
+func attachTotals(chain *models.OptionChain) {
+	if len(chain.Records.Totals) > 0 || chain.Filtered == (models.Filtered{}) || len(chain.Records.Data) == 0 {
+		return
+	}
+	expiry := chain.Records.Data[0].ExpiryDate
+	for _, row := range chain.Records.Data {
+		if row.ExpiryDate != expiry {
+			return
+		}
+	}
+	chain.Records.Totals = []models.ChainTotals{totalsFor(expiry, chain.Filtered)}
+}
+
+// rebaseChain moves a capture taken at capturedAt onto today's date,
+// keeping its time of day and shifting expiry dates by the same number of
+// days so time-to-expiry is preserved.
+func rebaseChain(chain models.OptionChain, capturedAt, today time.Time) models.OptionChain {
+	capturedDay := time.Date(capturedAt.Year(), capturedAt.Month(), capturedAt.Day(), 0, 0, 0, 0, time.UTC)
+	targetDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(targetDay.Sub(capturedDay).Hours() / 24)
+
+	shift := func(s, layout string) string {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return s
+		}
+		return t.AddDate(0, 0, days).Format(layout)
+	}
+	shiftOption := func(o *models.Option) *models.Option {
+		if o == nil {
+			return nil
+		}
+		shifted := *o
+		shifted.ExpiryDate = shift(o.ExpiryDate, contractDateLayout)
+		return &shifted
+	}
+
+	out := chain
+	out.Records.TimeStamp = shift(chain.Records.TimeStamp, timestampLayout)
+
+	out.Records.ExpiryDates = make([]string, len(chain.Records.ExpiryDates))
+	for i, e := range chain.Records.ExpiryDates {
+		out.Records.ExpiryDates[i] = shift(e, expiryLayout)
+	}
+
+	out.Records.Totals = make([]models.ChainTotals, len(chain.Records.Totals))
+	for i, t := range chain.Records.Totals {
+		t.ExpiryDate = shift(t.ExpiryDate, expiryLayout)
+		out.Records.Totals[i] = t
+	}
+
+	out.Records.Data = make([]models.OptionData, len(chain.Records.Data))
+	for i, row := range chain.Records.Data {
+		row.ExpiryDate = shift(row.ExpiryDate, expiryLayout)
+		row.CE = shiftOption(row.CE)
+		row.PE = shiftOption(row.PE)
+		out.Records.Data[i] = row
+	}
+
+	return out
+}
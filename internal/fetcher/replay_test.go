@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"server/internal/models"
+	"server/internal/symbols"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayDataWritesCapturesInOrder(t *testing.T) {
+	w := &recordingWriter{}
+	fs := newTestService(t, nil, "next:1")
+	fs.Writers = map[string]Writer{testSymbol.Name: w}
+
+	err := fs.ReplayData(context.Background(), discardLogger(), ReplayOptions{Dir: "testdata", Speed: 0, Rebase: true})
+	if err != nil {
+		t.Fatalf("ReplayData: %v", err)
+	}
+
+	if len(w.writes) != 2 {
+		t.Fatalf("got %d writes, want 2", len(w.writes))
+	}
+
+	loc, _ := time.LoadLocation("Asia/Kolkata")
+	today := time.Now().In(loc).Format("02-Jan-2006")
+	for i, wantClock := range []string{"11:43:19", "11:46:19"} {
+		if got := w.writes[i].TimeStamp; got != today+" "+wantClock {
+			t.Errorf("write %d: timestamp %q, want %q", i, got, today+" "+wantClock)
+		}
+	}
+	if w.writes[1].UnderlyingValue != 24281.4 {
+		t.Errorf("second capture underlying = %v", w.writes[1].UnderlyingValue)
+	}
+}
+
+func TestLoadCapturesJSONL(t *testing.T) {
+	dir := t.TempDir()
+	sym := symbols.Symbol{Name: "BANKNIFTY", Kind: symbols.Indices}
+
+	var lines []string
+	for _, ts := range []string{"17-Jul-2026 09:21:00", "17-Jul-2026 09:18:00"} {
+		raw, err := json.Marshal(models.Records{TimeStamp: ts, ExpiryDates: []string{"28-Jul-2026"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(raw))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "banknifty.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	captures, err := LoadCaptures(dir, sym, time.UTC)
+	if err != nil {
+		t.Fatalf("LoadCaptures: %v", err)
+	}
+	if len(captures) != 2 || captures[0].Chain.Records.TimeStamp != "17-Jul-2026 09:18:00" {
+		t.Fatalf("captures not loaded in time order: %+v", captures)
+	}
+}
+
+func TestRebaseChainShiftsExpiries(t *testing.T) {
+	chain := testChain("17-Jul-2026 11:43:19", "21-Jul-2026")
+	chain.Records.Data[0].CE.ExpiryDate = "21-07-2026"
+
+	capturedAt := time.Date(2026, time.July, 17, 11, 43, 19, 0, time.UTC)
+	today := time.Date(2026, time.October, 17, 8, 0, 0, 0, time.UTC)
+	got := rebaseChain(chain, capturedAt, today)
+
+	if got.Records.TimeStamp != "17-Oct-2026 11:43:19" {
+		t.Errorf("timestamp = %q", got.Records.TimeStamp)
+	}
+	if got.Records.ExpiryDates[0] != "21-Oct-2026" || got.Records.Data[0].ExpiryDate != "21-Oct-2026" {
+		t.Errorf("expiries not shifted: %v / %v", got.Records.ExpiryDates, got.Records.Data[0].ExpiryDate)
+	}
+	if got.Records.Data[0].CE.ExpiryDate != "21-10-2026" {
+		t.Errorf("contract expiry = %q", got.Records.Data[0].CE.ExpiryDate)
+	}
+	if chain.Records.Data[0].CE.ExpiryDate != "21-07-2026" {
+		t.Error("rebase modified the original capture")
+	}
+}
@@ -0,0 +1,24 @@
+package fetcher
+
+import (
+	"context"
+	"server/internal/models"
+	"server/internal/symbols"
+)
+
+// OptionChainSource is where FetcherService gets option chains from. The
+// live implementations are Browser (headless Chrome) and HTTPClient (plain
+// net/http); FileSource replays captured responses from disk and
+// FakeSource serves canned responses in tests.
+type OptionChainSource interface {
+	// FetchOptionChain returns sym's chain for the expiries selected by
+	// rule, merged into a single Records.
+	FetchOptionChain(ctx context.Context, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error)
+}
+
+var (
+	_ OptionChainSource = (*Browser)(nil)
+	_ OptionChainSource = (*HTTPClient)(nil)
+	_ OptionChainSource = (*FileSource)(nil)
+	_ OptionChainSource = (*FakeSource)(nil)
+)
@@ -3,6 +3,7 @@ package fetcher
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	redis "github.com/redis/go-redis/v9"
 
@@ -39,7 +40,27 @@ func (sw *StreamWriter) Write(ctx context.Context, records models.Records) error
 
 }
 
+// Delete removes the stream for the next trading day. It refuses while a
+// consumer group still has entries pending or undelivered, since the
+// processor only acknowledges a snapshot once it is in the database; the
+// fetcher retries on its next tick.
 func (sw *StreamWriter) Delete(ctx context.Context) error {
+	n, err := sw.writer.Exists(ctx, sw.stream).Result()
+	if err != nil || n == 0 {
+		return err
+	}
+
+	groups, err := sw.writer.XInfoGroups(ctx, sw.stream).Result()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.Pending > 0 || g.Lag > 0 {
+			return fmt.Errorf("stream %s has %d pending and %d undelivered entries for group %s",
+				sw.stream, g.Pending, g.Lag, g.Name)
+		}
+	}
+
 	return sw.writer.Del(ctx, sw.stream).Err()
 }
 
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"server/internal/models"
+	"server/internal/symbols"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,7 +20,8 @@ import (
 
 const (
 	userAgent         = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
-	optionChainPage   = "https://www.nseindia.com/option-chain"
+	nseBaseURL        = "https://www.nseindia.com"
+	optionChainPage   = nseBaseURL + "/option-chain"
 	cookieRefreshTTL  = 10 * time.Minute
 	navigationTimeout = 30 * time.Second
 )
@@ -28,6 +32,9 @@ const (
 // tick (as often as once a second before market open), so the tab is reused
 // and cookies are only refreshed periodically.
 type Browser struct {
+	// Archiver, if set, receives every raw response fetched.
+	Archiver *Archiver
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -111,7 +118,8 @@ func (b *Browser) refreshSession(ctx context.Context, force bool) error {
 // fetchJSON runs a fetch() from inside the already-loaded nseindia.com page
 // so the request carries the page's session cookies and headers exactly as
 // the site's own frontend would send them, then unmarshals the JSON body.
-func (b *Browser) fetchJSON(ctx context.Context, url string, out any) error {
+// The raw body is returned for archiving.
+func (b *Browser) fetchJSON(ctx context.Context, url string, out any) ([]byte, error) {
 	runCtx, cancel := b.withDeadline(ctx, navigationTimeout)
 	defer cancel()
 
@@ -127,12 +135,12 @@ func (b *Browser) fetchJSON(ctx context.Context, url string, out any) error {
 		}),
 	)
 	if err != nil {
-		return fmt.Errorf("in-page fetch of %s failed: %w", url, err)
+		return nil, fmt.Errorf("in-page fetch of %s failed: %w", url, err)
 	}
 
 	body = strings.TrimSpace(body)
 	if body == "" {
-		return fmt.Errorf("empty response body from %s", url)
+		return nil, fmt.Errorf("empty response body from %s", url)
 	}
 
 	if err := json.Unmarshal([]byte(body), out); err != nil {
@@ -146,48 +154,77 @@ func (b *Browser) fetchJSON(ctx context.Context, url string, out any) error {
 		if len(preview) > 200 {
 			preview = preview[:200]
 		}
-		return fmt.Errorf("json unmarshal failed for %s: %w | body preview: %s", url, err, preview)
+		return nil, fmt.Errorf("json unmarshal failed for %s: %w | body preview: %s", url, err, preview)
 	}
 
-	return nil
+	return []byte(body), nil
+}
+
+// contractInfoURL and optionChainURL build the NSE API URLs for sym under
+// base. The symbol is escaped since some F&O stocks (e.g. M&M) contain
+// reserved characters.
+func contractInfoURL(base string, sym symbols.Symbol) string {
+	return fmt.Sprintf("%s/api/option-chain-contract-info?symbol=%s&type=%s",
+		base, url.QueryEscape(sym.Name), sym.Kind)
 }
 
-func (b *Browser) getOptionChain(ctx context.Context) (models.OptionChain, error) {
+func optionChainURL(base string, sym symbols.Symbol, expiry string) string {
+	return fmt.Sprintf("%s/api/option-chain-v3?type=%s&symbol=%s&expiry=%s",
+		base, sym.Kind, url.QueryEscape(sym.Name), url.QueryEscape(expiry))
+}
+
+// FetchOptionChain fetches the expiries of sym selected by rule through the
+// shared headless Chrome tab.
+func (b *Browser) FetchOptionChain(ctx context.Context, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error) {
 	if err := b.refreshSession(ctx, false); err != nil {
 		return models.OptionChain{}, fmt.Errorf("cookie setup failed: %w", err)
 	}
+	return fetchOptionChain(ctx, b.fetchJSON, nseBaseURL, b.Archiver, sym, rule)
+}
+
+// fetchJSONFunc fetches url and unmarshals its JSON body into out, using
+// whichever session a source keeps, and returns the raw body.
+type fetchJSONFunc func(ctx context.Context, url string, out any) ([]byte, error)
+
+// fetchOptionChain resolves the expiries of sym selected by rule from the
+// contract-info endpoint under base, then fetches each with one
+// option-chain-v3 call and merges them into a single chain. Each raw
+// response is handed to archiver, if set.
+func fetchOptionChain(ctx context.Context, fetchJSON fetchJSONFunc, base string, archiver *Archiver, sym symbols.Symbol, rule ExpiryRule) (models.OptionChain, error) {
+	snapshotAt := time.Now()
 
 	var contractData struct {
 		ExpiryDates []string `json:"expiryDates"`
 	}
-	contractInfoURL := "https://www.nseindia.com/api/option-chain-contract-info?symbol=NIFTY&type=Indices"
-	if err := b.fetchJSON(ctx, contractInfoURL, &contractData); err != nil {
+	body, err := fetchJSON(ctx, contractInfoURL(base, sym), &contractData)
+	if err != nil {
 		return models.OptionChain{}, fmt.Errorf("failed to fetch contract info: %w", err)
 	}
+	archiver.Archive(ctx, sym, snapshotAt, contractInfoArchiveName, body)
 
-	if len(contractData.ExpiryDates) < 2 {
-		return models.OptionChain{}, fmt.Errorf("not enough expiry dates available")
+	selected := rule.Select(contractData.ExpiryDates)
+	if len(selected) == 0 {
+		return models.OptionChain{}, fmt.Errorf("no listed expiry matches rule %q (listed: %v)", rule, contractData.ExpiryDates)
 	}
 
-	firstExpiry := contractData.ExpiryDates[0]
-	secondExpiry := contractData.ExpiryDates[1]
-
 	var allData []models.OptionData
+	var totals []models.ChainTotals
+	var strikes models.StrikePrices
 	var expiryDates []string
 	var timestamp string
 	var underlyingValue float64
 	seen := make(map[string]struct{})
 
-	for _, expiry := range []string{firstExpiry, secondExpiry} {
-		url := fmt.Sprintf("https://www.nseindia.com/api/option-chain-v3?type=Indices&symbol=NIFTY&expiry=%s", expiry)
-
+	for _, expiry := range selected {
 		var optionData models.OptionChain
-		if err := b.fetchJSON(ctx, url, &optionData); err != nil {
+		body, err := fetchJSON(ctx, optionChainURL(base, sym, expiry), &optionData)
+		if err != nil {
 			return models.OptionChain{}, fmt.Errorf("failed to fetch data for expiry %s: %w", expiry, err)
 		}
+		archiver.Archive(ctx, sym, snapshotAt, optionChainArchiveName(expiry), body)
 
 		// Each call is scoped to a single expiry, so its rows shouldn't
-		// overlap with the other call's, but dedupe by contract identifier
+		// overlap with the other calls', but dedupe by contract identifier
 		// (unique per symbol+expiry+type+strike) to be safe rather than
 		// relying on that.
 		for _, row := range optionData.Records.Data {
@@ -206,10 +243,12 @@ func (b *Browser) getOptionChain(ctx context.Context) (models.OptionChain, error
 
 		// The contract-info/option-chain-v3 endpoints return the full expiry
 		// catalog for the symbol regardless of which expiry was requested,
-		// so it's identical across both calls here - only capture it once.
+		// so it's identical across every call here - only capture it once.
 		if expiryDates == nil {
 			expiryDates = optionData.Records.ExpiryDates
 		}
+		totals = append(totals, totalsFor(expiry, optionData.Filtered))
+		strikes = mergeStrikes(strikes, optionData.Records.StrikePrices)
 		timestamp = optionData.Records.TimeStamp
 		underlyingValue = optionData.Records.UnderlyingValue
 	}
@@ -220,14 +259,27 @@ func (b *Browser) getOptionChain(ctx context.Context) (models.OptionChain, error
 			Data:            allData,
 			TimeStamp:       timestamp,
 			UnderlyingValue: underlyingValue,
+			StrikePrices:    strikes,
+			Totals:          totals,
 		},
 	}, nil
 }
 
-func isMarketHoliday(date time.Time, loc *time.Location) bool {
-	if date.In(loc).Weekday() == time.Saturday || date.In(loc).Weekday() == time.Sunday {
-		return true
+// totalsFor converts a single-expiry response's "filtered" block into the
+// per-expiry totals carried on Records.
+func totalsFor(expiry string, f models.Filtered) models.ChainTotals {
+	return models.ChainTotals{
+		ExpiryDate:    expiry,
+		CETotalOI:     f.CE.TotalOI,
+		CETotalVolume: f.CE.TotalVolume,
+		PETotalOI:     f.PE.TotalOI,
+		PETotalVolume: f.PE.TotalVolume,
 	}
+}
 
-	return false
+// mergeStrikes returns the sorted union of two strike lists.
+func mergeStrikes(a, b models.StrikePrices) models.StrikePrices {
+	out := append(slices.Clone(a), b...)
+	slices.Sort(out)
+	return slices.Compact(out)
 }
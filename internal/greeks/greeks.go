@@ -0,0 +1,143 @@
+// Package greeks prices European options and their sensitivities with
+// Black-Scholes-Merton (spot with a continuous dividend yield) and Black-76
+// (forward or futures price).
+package greeks
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// OptionType is a call or a put.
+type OptionType int
+
+const (
+	Call OptionType = iota
+	Put
+)
+
+// Inputs describe one option. Rates, yields and volatility are annualised
+// decimals: 0.2 is 20%.
+type Inputs struct {
+	// Underlying is the spot price for BlackScholes and the forward or
+	// futures price for Black76.
+	Underlying float64
+	Strike     float64
+	// Years is the time to expiry in years.
+	Years      float64
+	Volatility float64
+	// Rate is the continuously compounded risk-free rate.
+	Rate float64
+	// DividendYield is the continuous dividend yield. Black76 ignores it.
+	DividendYield float64
+}
+
+// Greeks is an option's price and sensitivities, in the units traders
+// quote: Theta per calendar day, Vega per volatility point (1%) and Rho per
+// 1% move in the rate.
+type Greeks struct {
+	Price float64
+	Delta float64
+	Gamma float64
+	Theta float64
+	Vega  float64
+	Rho   float64
+}
+
+// daysPerYear converts annual theta to per-day theta.
+const daysPerYear = 365
+
+// BlackScholes prices an option on a spot underlying paying a continuous
+// dividend yield (the Merton form). ok is false when the inputs cannot be
+// priced: a non-positive underlying, strike, time or volatility.
+func BlackScholes(typ OptionType, in Inputs) (g Greeks, ok bool) {
+	if in.Underlying <= 0 || in.Strike <= 0 || in.Years <= 0 || in.Volatility <= 0 {
+		return Greeks{}, false
+	}
+
+	s, k, t, v, r, q := in.Underlying, in.Strike, in.Years, in.Volatility, in.Rate, in.DividendYield
+	sqrtT := math.Sqrt(t)
+	d1 := (math.Log(s/k) + (r-q+v*v/2)*t) / (v * sqrtT)
+	d2 := d1 - v*sqrtT
+
+	divDiscount := math.Exp(-q * t)
+	discount := math.Exp(-r * t)
+	pdf := normPDF(d1)
+
+	g.Gamma = divDiscount * pdf / (s * v * sqrtT)
+	g.Vega = s * divDiscount * pdf * sqrtT / 100
+	decay := -s * divDiscount * pdf * v / (2 * sqrtT)
+
+	switch typ {
+	case Call:
+		g.Price = s*divDiscount*normCDF(d1) - k*discount*normCDF(d2)
+		g.Delta = divDiscount * normCDF(d1)
+		g.Theta = (decay - r*k*discount*normCDF(d2) + q*s*divDiscount*normCDF(d1)) / daysPerYear
+		g.Rho = k * t * discount * normCDF(d2) / 100
+	case Put:
+		g.Price = k*discount*normCDF(-d2) - s*divDiscount*normCDF(-d1)
+		g.Delta = -divDiscount * normCDF(-d1)
+		g.Theta = (decay + r*k*discount*normCDF(-d2) - q*s*divDiscount*normCDF(-d1)) / daysPerYear
+		g.Rho = -k * t * discount * normCDF(-d2) / 100
+	default:
+		return Greeks{}, false
+	}
+	return g, true
+}
+
+// Black76 prices an option on a forward or futures price. Delta and Gamma
+// are with respect to the forward, and Rho holds the forward fixed. ok is
+// false when the inputs cannot be priced.
+func Black76(typ OptionType, in Inputs) (Greeks, bool) {
+	// Black-76 is Black-Scholes-Merton with the forward as the underlying
+	// and a dividend yield equal to the rate, except for Rho: moving the
+	// rate with the forward held fixed only changes the discounting.
+	in.DividendYield = in.Rate
+	g, ok := BlackScholes(typ, in)
+	if !ok {
+		return Greeks{}, false
+	}
+	g.Rho = -in.Years * g.Price / 100
+	return g, true
+}
+
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// Rates are the market inputs Greeks need beyond what NSE reports.
+type Rates struct {
+	RiskFree      float64
+	DividendYield float64
+}
+
+// DefaultRiskFreeRate approximates the short-dated Indian government yield.
+const DefaultRiskFreeRate = 0.065
+
+// RatesFromEnv reads RISK_FREE_RATE (default DefaultRiskFreeRate) and
+// DIVIDEND_YIELD (default 0) as annualised decimals.
+func RatesFromEnv() (Rates, error) {
+	rates := Rates{RiskFree: DefaultRiskFreeRate}
+
+	if v := os.Getenv("RISK_FREE_RATE"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Rates{}, fmt.Errorf("RISK_FREE_RATE %q: %w", v, err)
+		}
+		rates.RiskFree = r
+	}
+	if v := os.Getenv("DIVIDEND_YIELD"); v != "" {
+		q, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Rates{}, fmt.Errorf("DIVIDEND_YIELD %q: %w", v, err)
+		}
+		rates.DividendYield = q
+	}
+	return rates, nil
+}
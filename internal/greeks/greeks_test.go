@@ -0,0 +1,113 @@
+package greeks
+
+import (
+	"math"
+	"testing"
+)
+
+func near(t *testing.T, name string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%s = %.5f, want %.5f ± %g", name, got, want, tol)
+	}
+}
+
+// Hull, Options, Futures, and Other Derivatives: a call on a non-dividend
+// stock with S=49, K=50, r=5%, σ=20% and 20 weeks to expiry.
+var hull = Inputs{Underlying: 49, Strike: 50, Years: 20.0 / 52, Volatility: 0.2, Rate: 0.05}
+
+func TestBlackScholesCallMatchesHull(t *testing.T) {
+	g, ok := BlackScholes(Call, hull)
+	if !ok {
+		t.Fatal("BlackScholes rejected valid inputs")
+	}
+
+	near(t, "price", g.Price, 2.40, 0.005)
+	near(t, "delta", g.Delta, 0.522, 0.0005)
+	near(t, "gamma", g.Gamma, 0.066, 0.0005)
+	near(t, "theta", g.Theta, -4.31/365, 0.00005)
+	near(t, "vega", g.Vega, 12.1/100, 0.0005)
+	near(t, "rho", g.Rho, 8.91/100, 0.00005)
+}
+
+func TestBlackScholesPutCallParity(t *testing.T) {
+	in := hull
+	in.DividendYield = 0.02
+
+	call, _ := BlackScholes(Call, in)
+	put, ok := BlackScholes(Put, in)
+	if !ok {
+		t.Fatal("BlackScholes rejected valid inputs")
+	}
+
+	// C - P = S e^{-qT} - K e^{-rT}
+	parity := in.Underlying*math.Exp(-in.DividendYield*in.Years) - in.Strike*math.Exp(-in.Rate*in.Years)
+	near(t, "call - put", call.Price-put.Price, parity, 1e-9)
+	near(t, "call delta - put delta", call.Delta-put.Delta, math.Exp(-in.DividendYield*in.Years), 1e-9)
+	near(t, "put gamma", put.Gamma, call.Gamma, 1e-12)
+	near(t, "put vega", put.Vega, call.Vega, 1e-12)
+}
+
+// Hull's crude oil example: a European put on futures at 20, strike 20,
+// r=9%, σ=25% and 4 months to expiry is worth 1.12.
+func TestBlack76PutMatchesHull(t *testing.T) {
+	in := Inputs{Underlying: 20, Strike: 20, Years: 4.0 / 12, Volatility: 0.25, Rate: 0.09}
+
+	put, ok := Black76(Put, in)
+	if !ok {
+		t.Fatal("Black76 rejected valid inputs")
+	}
+	near(t, "price", put.Price, 1.12, 0.005)
+
+	// At the money forward, call and put are worth the same, and Rho holds
+	// the forward fixed so it is -T times the price.
+	call, _ := Black76(Call, in)
+	near(t, "call price", call.Price, put.Price, 1e-9)
+	near(t, "rho", put.Rho, -in.Years*put.Price/100, 1e-12)
+}
+
+func TestUnpriceableInputs(t *testing.T) {
+	for name, in := range map[string]Inputs{
+		"expired":        {Underlying: 100, Strike: 100, Years: 0, Volatility: 0.2},
+		"no volatility":  {Underlying: 100, Strike: 100, Years: 0.1, Volatility: 0},
+		"no underlying":  {Underlying: 0, Strike: 100, Years: 0.1, Volatility: 0.2},
+		"negative years": {Underlying: 100, Strike: 100, Years: -0.1, Volatility: 0.2},
+	} {
+		if _, ok := BlackScholes(Call, in); ok {
+			t.Errorf("%s: BlackScholes priced unpriceable inputs", name)
+		}
+	}
+}
+
+func TestImpliedVolatilityRoundTrips(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		typ  OptionType
+		in   Inputs
+	}{
+		{"hull call", Call, hull},
+		{"deep otm put", Put, Inputs{Underlying: 24000, Strike: 21000, Years: 7.0 / 365, Volatility: 0.35, Rate: 0.065}},
+		{"deep itm call", Call, Inputs{Underlying: 24000, Strike: 22000, Years: 30.0 / 365, Volatility: 0.12, Rate: 0.065}},
+		{"high vol", Put, Inputs{Underlying: 100, Strike: 100, Years: 0.5, Volatility: 1.8, Rate: 0.05}},
+	} {
+		g, _ := BlackScholes(tc.typ, tc.in)
+		iv, ok := ImpliedVolatility(tc.typ, g.Price, tc.in)
+		if !ok {
+			t.Errorf("%s: no volatility found for price %.6f", tc.name, g.Price)
+			continue
+		}
+		near(t, tc.name+" iv", iv, tc.in.Volatility, 1e-4)
+	}
+}
+
+func TestImpliedVolatilityRejectsArbitrage(t *testing.T) {
+	// Below intrinsic: no volatility prices a call on 49 struck at 40 at 5.
+	in := Inputs{Underlying: 49, Strike: 40, Years: 0.25, Rate: 0.05}
+	if iv, ok := ImpliedVolatility(Call, 5, in); ok {
+		t.Errorf("price below intrinsic solved to %v", iv)
+	}
+	// Above the underlying: a call is never worth more than the stock.
+	if iv, ok := ImpliedVolatility(Call, 60, in); ok {
+		t.Errorf("price above underlying solved to %v", iv)
+	}
+}
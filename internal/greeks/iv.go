@@ -0,0 +1,70 @@
+package greeks
+
+import "math"
+
+const (
+	// ivTolerance is how close, in price, a solved volatility must
+	// reprice the option.
+	ivTolerance = 1e-6
+	ivMaxIter   = 100
+
+	// minVolatility and maxVolatility bracket the bisection search: 0.01%
+	// to 1000% annualised covers anything an index option trades at.
+	minVolatility = 1e-4
+	maxVolatility = 10.0
+)
+
+// ImpliedVolatility solves BlackScholes for the volatility that prices the
+// option at price, ignoring in.Volatility. It runs Newton's method from a
+// Brenner-Subrahmanyam guess and falls back to bisection when Newton
+// leaves the bracket or stalls on a flat vega. ok is false when no
+// volatility reproduces price: it is outside the no-arbitrage bounds or
+// the other inputs cannot be priced.
+func ImpliedVolatility(typ OptionType, price float64, in Inputs) (float64, bool) {
+	if price <= 0 || in.Underlying <= 0 || in.Strike <= 0 || in.Years <= 0 {
+		return 0, false
+	}
+
+	priceAt := func(v float64) (Greeks, bool) {
+		in.Volatility = v
+		return BlackScholes(typ, in)
+	}
+
+	lo, hi := minVolatility, maxVolatility
+	low, ok := priceAt(lo)
+	if !ok {
+		return 0, false
+	}
+	high, _ := priceAt(hi)
+	if price < low.Price-ivTolerance || price > high.Price+ivTolerance {
+		return 0, false
+	}
+
+	v := math.Sqrt(2*math.Pi/in.Years) * price / in.Underlying
+	if v <= lo || v >= hi {
+		v = 0.2
+	}
+
+	for range ivMaxIter {
+		g, _ := priceAt(v)
+		diff := g.Price - price
+		if math.Abs(diff) < ivTolerance {
+			return v, true
+		}
+		if diff > 0 {
+			hi = v
+		} else {
+			lo = v
+		}
+
+		// Vega is per volatility point; Newton wants it per unit.
+		next := v - diff/(g.Vega*100)
+		if g.Vega <= 0 || math.IsNaN(next) || next <= lo || next >= hi {
+			next = (lo + hi) / 2
+		}
+		v = next
+	}
+
+	// The bracket has narrowed below anything a quote can distinguish.
+	return v, hi-lo < ivTolerance
+}
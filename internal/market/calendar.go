@@ -0,0 +1,229 @@
+package market
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// embeddedCalendar is the NSE holiday list shipped with the binary. A newer
+// list can be supplied at runtime through MARKET_CALENDAR_FILE without a
+// rebuild.
+//
+//go:embed nse_calendar.json
+var embeddedCalendar []byte
+
+const (
+	dateLayout  = "2006-01-02"
+	clockLayout = "15:04"
+
+	// reloadCheckInterval bounds how often a file-backed calendar stats its
+	// file for changes.
+	reloadCheckInterval = time.Minute
+)
+
+// Entry is one dated line of the calendar file. Holidays only need Date;
+// special sessions may also set Open/Close ("HH:MM", IST) when they differ
+// from the regular Timings, e.g. Muhurat trading, and must then open
+// before they close. A date is listed as a holiday or a session, not both.
+type Entry struct {
+	Date        string `json:"date"`
+	Description string `json:"description"`
+	Open        string `json:"open,omitempty"`
+	Close       string `json:"close,omitempty"`
+}
+
+type calendarFile struct {
+	Holidays []Entry `json:"holidays"`
+	Sessions []Entry `json:"sessions"`
+}
+
+type calendarData struct {
+	holidays map[string]Entry
+	sessions map[string]Entry
+}
+
+// Calendar answers whether the exchange trades on a given date. Weekends
+// are closed unless the calendar lists a special session for that date;
+// listed holidays are closed. Intraday phases are derived from it by
+// Schedule.
+//
+// A Calendar loaded from a file re-reads it when its modification time
+// changes, so yearly holiday lists and newly announced special sessions can
+// be dropped in without restarting the services.
+type Calendar struct {
+	loc    *time.Location
+	path   string
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	data      calendarData
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// LoadCalendar builds a Calendar from the file at path, or from the
+// embedded NSE list when path is empty.
+func LoadCalendar(path string, loc *time.Location, logger *slog.Logger) (*Calendar, error) {
+	c := &Calendar{loc: loc, path: path, logger: logger}
+
+	if path == "" {
+		data, err := parseCalendar(embeddedCalendar)
+		if err != nil {
+			return nil, fmt.Errorf("embedded calendar: %w", err)
+		}
+		c.data = data
+		return c, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat calendar file: %w", err)
+	}
+	if err := c.loadFile(info.ModTime()); err != nil {
+		return nil, err
+	}
+	c.checkedAt = time.Now()
+	return c, nil
+}
+
+// CalendarFromEnv loads the calendar named by MARKET_CALENDAR_FILE, or the
+// embedded one when it is unset.
+func CalendarFromEnv(loc *time.Location, logger *slog.Logger) (*Calendar, error) {
+	return LoadCalendar(os.Getenv("MARKET_CALENDAR_FILE"), loc, logger)
+}
+
+// IsHoliday reports whether the exchange is closed for the whole of t's
+// date: a listed holiday, or a weekend without a special session.
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	_, ok := c.Session(t)
+	return !ok
+}
+
+// Session reports whether the exchange trades on t's date. For special
+// sessions the returned Entry carries any Open/Close override; on ordinary
+// trading days it is empty.
+func (c *Calendar) Session(t time.Time) (Entry, bool) {
+	t = t.In(c.loc)
+	data := c.current()
+	key := t.Format(dateLayout)
+
+	if entry, special := data.sessions[key]; special {
+		return entry, true
+	}
+	if _, holiday := data.holidays[key]; holiday {
+		return Entry{}, false
+	}
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return Entry{}, false
+	}
+	return Entry{}, true
+}
+
+// Describe returns the calendar's description for t's date, e.g. the
+// holiday name, or "" when the date is an ordinary day.
+func (c *Calendar) Describe(t time.Time) string {
+	data := c.current()
+	key := t.In(c.loc).Format(dateLayout)
+	if e, ok := data.sessions[key]; ok {
+		return e.Description
+	}
+	return data.holidays[key].Description
+}
+
+// current returns the calendar data, first re-reading the backing file if
+// it changed since the last check. A file that fails to parse is logged and
+// the previous data kept, so a bad edit can't take the services down.
+func (c *Calendar) current() calendarData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || time.Since(c.checkedAt) < reloadCheckInterval {
+		return c.data
+	}
+	c.checkedAt = time.Now()
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		c.logger.Warn("Failed to stat calendar file, keeping previous calendar",
+			slog.String("path", c.path), slog.String("err", err.Error()))
+		return c.data
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return c.data
+	}
+
+	if err := c.loadFile(info.ModTime()); err != nil {
+		c.logger.Error("Failed to reload calendar file, keeping previous calendar",
+			slog.String("path", c.path), slog.String("err", err.Error()))
+		// Don't retry the same broken file every minute.
+		c.modTime = info.ModTime()
+		return c.data
+	}
+
+	c.logger.Info("Reloaded market calendar", slog.String("path", c.path))
+	return c.data
+}
+
+func (c *Calendar) loadFile(modTime time.Time) error {
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read calendar file: %w", err)
+	}
+	data, err := parseCalendar(raw)
+	if err != nil {
+		return fmt.Errorf("calendar file %s: %w", c.path, err)
+	}
+	c.data = data
+	c.modTime = modTime
+	return nil
+}
+
+func parseCalendar(raw []byte) (calendarData, error) {
+	var file calendarFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return calendarData{}, fmt.Errorf("invalid calendar json: %w", err)
+	}
+
+	data := calendarData{
+		holidays: make(map[string]Entry, len(file.Holidays)),
+		sessions: make(map[string]Entry, len(file.Sessions)),
+	}
+
+	for _, e := range file.Holidays {
+		if _, err := time.Parse(dateLayout, e.Date); err != nil {
+			return calendarData{}, fmt.Errorf("holiday %q: invalid date", e.Date)
+		}
+		data.holidays[e.Date] = e
+	}
+
+	for _, e := range file.Sessions {
+		if _, err := time.Parse(dateLayout, e.Date); err != nil {
+			return calendarData{}, fmt.Errorf("session %q: invalid date", e.Date)
+		}
+		if _, ok := data.holidays[e.Date]; ok {
+			return calendarData{}, fmt.Errorf("session %s: date is also listed as a holiday", e.Date)
+		}
+		var clocks []time.Time
+		for _, hhmm := range []string{e.Open, e.Close} {
+			if hhmm == "" {
+				continue
+			}
+			t, err := time.Parse(clockLayout, hhmm)
+			if err != nil {
+				return calendarData{}, fmt.Errorf("session %s: invalid time %q", e.Date, hhmm)
+			}
+			clocks = append(clocks, t)
+		}
+		if len(clocks) == 2 && !clocks[0].Before(clocks[1]) {
+			return calendarData{}, fmt.Errorf("session %s: open %s is not before close %s", e.Date, e.Open, e.Close)
+		}
+		data.sessions[e.Date] = e
+	}
+
+	return data, nil
+}
@@ -0,0 +1,93 @@
+package market
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mustIST(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestEmbeddedCalendar(t *testing.T) {
+	loc := mustIST(t)
+	cal, err := LoadCalendar("", loc, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, loc) }
+
+	if !cal.IsHoliday(at(2026, time.January, 26)) {
+		t.Error("Republic Day should be a holiday")
+	}
+	if !cal.IsHoliday(at(2026, time.October, 17)) {
+		t.Error("Saturday should be closed")
+	}
+	if cal.IsHoliday(at(2026, time.October, 19)) {
+		t.Error("ordinary Monday should be open")
+	}
+
+	if _, ok := cal.Session(at(2025, time.February, 1)); !ok {
+		t.Error("budget day Saturday session should be open")
+	}
+	if e, ok := cal.Session(at(2025, time.October, 21)); !ok || e.Open != "13:45" {
+		t.Errorf("Muhurat session should open on Diwali, got %+v ok=%v", e, ok)
+	}
+}
+
+func TestParseCalendarRejectsBadSessions(t *testing.T) {
+	tests := map[string]string{
+		"open after close": `{"sessions": [{"date": "2026-11-08", "open": "14:45", "close": "13:45"}]}`,
+		"open at close":    `{"sessions": [{"date": "2026-11-08", "open": "13:45", "close": "13:45"}]}`,
+		"holiday and session": `{"holidays": [{"date": "2026-11-08", "description": "Diwali"}],
+			"sessions": [{"date": "2026-11-08", "description": "Muhurat Trading"}]}`,
+	}
+	for name, raw := range tests {
+		if _, err := parseCalendar([]byte(raw)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCalendarFileReload(t *testing.T) {
+	loc := mustIST(t)
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	if err := os.WriteFile(path, []byte(`{"holidays": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cal, err := LoadCalendar(path, loc, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2027, time.January, 26, 12, 0, 0, 0, loc)
+	if cal.IsHoliday(day) {
+		t.Fatal("date should be open before the file lists it")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"holidays": [{"date": "2027-01-26", "description": "Republic Day"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	cal.checkedAt = time.Time{}
+
+	if !cal.IsHoliday(day) {
+		t.Error("updated file should have been picked up")
+	}
+	if got := cal.Describe(day); got != "Republic Day" {
+		t.Errorf("Describe = %q", got)
+	}
+}
@@ -0,0 +1,276 @@
+package market
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Phase is the part of the trading day the exchange is in.
+type Phase string
+
+const (
+	// Closed covers non-trading days and the hours before pre-open.
+	Closed Phase = "closed"
+	// PreOpen is the pre-open order entry window.
+	PreOpen Phase = "pre_open"
+	// PreOpenMatching is the pre-open order matching and buffer window
+	// leading into the normal session.
+	PreOpenMatching Phase = "pre_open_matching"
+	// Normal is continuous trading.
+	Normal Phase = "normal"
+	// PostClose runs from the close until the daily reset.
+	PostClose Phase = "post_close"
+	// Reset is the end-of-day window in which per-day state (such as the
+	// Redis streams) is cleared for the next trading day.
+	Reset Phase = "reset"
+)
+
+// DefaultTimings are NSE's regular session boundaries, in IST.
+var DefaultTimings = Timings{
+	PreOpen:  "09:00",
+	Matching: "09:08",
+	Open:     "09:15",
+	Close:    "15:30",
+	Reset:    "23:00",
+}
+
+// Timings are the regular daily phase boundaries as "HH:MM" in the
+// exchange's timezone. They can be overridden with MARKET_TIMINGS, e.g.
+// "pre_open=09:00,matching=09:08,open=09:15,close=15:30,reset=23:00";
+// keys that are left out keep their defaults.
+type Timings struct {
+	PreOpen  string
+	Matching string
+	Open     string
+	Close    string
+	Reset    string
+}
+
+// ParseTimings applies the comma-separated key=HH:MM overrides in spec to
+// DefaultTimings.
+func ParseTimings(spec string) (Timings, error) {
+	t := DefaultTimings
+
+	for _, kv := range strings.Split(spec, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return Timings{}, fmt.Errorf("invalid timing %q, want key=HH:MM", kv)
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "pre_open":
+			t.PreOpen = value
+		case "matching":
+			t.Matching = value
+		case "open":
+			t.Open = value
+		case "close":
+			t.Close = value
+		case "reset":
+			t.Reset = value
+		default:
+			return Timings{}, fmt.Errorf("unknown timing %q", key)
+		}
+	}
+
+	if err := t.validate(); err != nil {
+		return Timings{}, err
+	}
+	return t, nil
+}
+
+// TimingsFromEnv parses MARKET_TIMINGS, falling back to DefaultTimings.
+func TimingsFromEnv() (Timings, error) {
+	return ParseTimings(os.Getenv("MARKET_TIMINGS"))
+}
+
+func (t Timings) validate() error {
+	order := []string{t.PreOpen, t.Matching, t.Open, t.Close, t.Reset}
+	var prev time.Time
+	for i, hhmm := range order {
+		at, err := time.Parse(clockLayout, hhmm)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", hhmm)
+		}
+		if i > 0 && !at.After(prev) {
+			return fmt.Errorf("timings must be increasing: pre_open < matching < open < close < reset")
+		}
+		prev = at
+	}
+	return nil
+}
+
+// State is the exchange's phase at a point in time.
+type State struct {
+	Phase Phase
+	// TradingDay reports whether the date has a session at all, which
+	// tells a Closed weekday morning apart from a holiday.
+	TradingDay bool
+	// Open and Close are the normal session's bounds on a trading day.
+	Open  time.Time
+	Close time.Time
+	// Next is when the phase next changes.
+	Next time.Time
+}
+
+// BeforeOpen reports whether t is on a trading day ahead of the normal
+// session.
+func (s State) BeforeOpen() bool {
+	switch s.Phase {
+	case Closed, PreOpen, PreOpenMatching:
+		return s.TradingDay
+	}
+	return false
+}
+
+// AfterClose reports whether the normal session has ended for the day.
+func (s State) AfterClose() bool {
+	return s.Phase == PostClose || s.Phase == Reset
+}
+
+// Schedule combines the holiday Calendar with the daily Timings to give
+// the single source of truth for session phases shared by the fetcher,
+// the processor and the HTTP handlers.
+type Schedule struct {
+	calendar   *Calendar
+	timings    Timings
+	loc        *time.Location
+	alwaysOpen bool
+}
+
+// NewSchedule builds a Schedule. timings must be valid, as returned by
+// ParseTimings.
+func NewSchedule(calendar *Calendar, timings Timings) *Schedule {
+	return &Schedule{calendar: calendar, timings: timings, loc: calendar.loc}
+}
+
+// ScheduleFromEnv loads the timings from the environment. Setting
+// MARKET_ALWAYS_OPEN=true makes every moment part of the normal session,
+// for running the pipeline against replayed captures outside market hours.
+func ScheduleFromEnv(calendar *Calendar) (*Schedule, error) {
+	timings, err := TimingsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("MARKET_TIMINGS: %w", err)
+	}
+	s := NewSchedule(calendar, timings)
+	if os.Getenv("MARKET_ALWAYS_OPEN") == "true" {
+		s = s.AlwaysOpen()
+	}
+	return s, nil
+}
+
+// AlwaysOpen returns a copy of s that reports the normal session at all
+// times, ignoring holidays and timings.
+func (s *Schedule) AlwaysOpen() *Schedule {
+	open := *s
+	open.alwaysOpen = true
+	return &open
+}
+
+// Location is the exchange timezone the schedule works in.
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// Calendar returns the holiday calendar behind the schedule.
+func (s *Schedule) Calendar() *Calendar {
+	return s.calendar
+}
+
+// At reports the phase at t.
+func (s *Schedule) At(t time.Time) State {
+	t = t.In(s.loc)
+
+	if s.alwaysOpen {
+		dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+		midnight := dayStart.AddDate(0, 0, 1)
+		return State{Phase: Normal, TradingDay: true, Open: dayStart, Close: midnight, Next: midnight}
+	}
+
+	b, ok := s.boundaries(t)
+	if !ok {
+		return State{Phase: Closed, Next: s.nextPreOpen(t)}
+	}
+
+	state := State{TradingDay: true, Open: b.open, Close: b.close}
+	midnight := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+
+	switch {
+	case t.Before(b.preOpen):
+		state.Phase, state.Next = Closed, b.preOpen
+	case t.Before(b.matching):
+		state.Phase, state.Next = PreOpen, b.matching
+	case t.Before(b.open):
+		state.Phase, state.Next = PreOpenMatching, b.open
+	case t.Before(b.close):
+		state.Phase, state.Next = Normal, b.close
+	case t.Before(b.reset):
+		state.Phase, state.Next = PostClose, b.reset
+	default:
+		state.Phase, state.Next = Reset, midnight
+	}
+	return state
+}
+
+type dayBoundaries struct {
+	preOpen, matching, open, close, reset time.Time
+}
+
+// boundaries returns t's date's phase boundaries, or ok=false on a
+// non-trading day. Special sessions with their own open time keep the
+// regular pre-open lead time ahead of it.
+func (s *Schedule) boundaries(t time.Time) (dayBoundaries, bool) {
+	entry, ok := s.calendar.Session(t)
+	if !ok {
+		return dayBoundaries{}, false
+	}
+
+	clock := func(hhmm string) time.Time {
+		// Validated when the timings and calendar were parsed.
+		at, _ := time.Parse(clockLayout, hhmm)
+		return time.Date(t.Year(), t.Month(), t.Day(), at.Hour(), at.Minute(), 0, 0, s.loc)
+	}
+
+	b := dayBoundaries{
+		preOpen:  clock(s.timings.PreOpen),
+		matching: clock(s.timings.Matching),
+		open:     clock(s.timings.Open),
+		close:    clock(s.timings.Close),
+		reset:    clock(s.timings.Reset),
+	}
+
+	if entry.Open != "" {
+		open := clock(entry.Open)
+		shift := open.Sub(b.open)
+		b.preOpen = b.preOpen.Add(shift)
+		b.matching = b.matching.Add(shift)
+		b.open = open
+	}
+	if entry.Close != "" {
+		b.close = clock(entry.Close)
+	}
+	if !b.reset.After(b.close) {
+		b.reset = b.close
+	}
+
+	return b, true
+}
+
+// nextPreOpen finds the start of the next trading day's pre-open after t,
+// looking at most a year ahead.
+func (s *Schedule) nextPreOpen(t time.Time) time.Time {
+	for i := 1; i <= 366; i++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, s.loc)
+		if b, ok := s.boundaries(day); ok {
+			return b.preOpen
+		}
+	}
+	return time.Time{}
+}
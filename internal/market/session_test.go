@@ -0,0 +1,77 @@
+package market
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestScheduleAt(t *testing.T) {
+	loc := mustIST(t)
+	cal, err := LoadCalendar("", loc, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSchedule(cal, DefaultTimings)
+
+	at := func(m time.Month, d, hh, mm int) time.Time { return time.Date(2026, m, d, hh, mm, 0, 0, loc) }
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		phase Phase
+		next  time.Time
+	}{
+		{"early morning", at(time.October, 19, 8, 0), Closed, at(time.October, 19, 9, 0)},
+		{"pre-open", at(time.October, 19, 9, 3), PreOpen, at(time.October, 19, 9, 8)},
+		{"matching", at(time.October, 19, 9, 10), PreOpenMatching, at(time.October, 19, 9, 15)},
+		{"normal", at(time.October, 19, 12, 0), Normal, at(time.October, 19, 15, 30)},
+		{"post close", at(time.October, 19, 16, 0), PostClose, at(time.October, 19, 23, 0)},
+		{"reset", at(time.October, 19, 23, 30), Reset, at(time.October, 20, 0, 0)},
+		// Saturday, then Dussehra on the 20th: next session is the 21st.
+		{"weekend", at(time.October, 17, 12, 0), Closed, at(time.October, 19, 9, 0)},
+		{"holiday", at(time.October, 20, 12, 0), Closed, at(time.October, 21, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		got := s.At(tt.at)
+		if got.Phase != tt.phase || !got.Next.Equal(tt.next) {
+			t.Errorf("%s: got phase=%s next=%v, want phase=%s next=%v", tt.name, got.Phase, got.Next, tt.phase, tt.next)
+		}
+	}
+}
+
+func TestScheduleSpecialSessionHours(t *testing.T) {
+	loc := mustIST(t)
+	cal, err := LoadCalendar("", loc, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSchedule(cal, DefaultTimings)
+
+	// Muhurat trading on 21-Oct-2025 ran 13:45-14:45.
+	state := s.At(time.Date(2025, time.October, 21, 13, 40, 0, 0, loc))
+	if state.Phase != PreOpenMatching {
+		t.Errorf("got phase %s ahead of the Muhurat open, want %s", state.Phase, PreOpenMatching)
+	}
+	state = s.At(time.Date(2025, time.October, 21, 14, 0, 0, 0, loc))
+	if state.Phase != Normal || state.Close.Hour() != 14 || state.Close.Minute() != 45 {
+		t.Errorf("got phase=%s close=%v during Muhurat", state.Phase, state.Close)
+	}
+}
+
+func TestParseTimings(t *testing.T) {
+	got, err := ParseTimings("open=09:30, close=15:45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Open != "09:30" || got.Close != "15:45" || got.PreOpen != DefaultTimings.PreOpen {
+		t.Errorf("unexpected timings %+v", got)
+	}
+
+	for _, spec := range []string{"open", "open=9", "close=09:00", "lunch=12:00"} {
+		if _, err := ParseTimings(spec); err == nil {
+			t.Errorf("ParseTimings(%q): expected error", spec)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
 
 type Option struct {
 	StrikePrice           float64 `json:"strikePrice"`
@@ -17,10 +22,10 @@ type Option struct {
 	PChange               float64 `json:"pChange"`
 	TotalBuyQuantity      int     `json:"totalBuyQuantity"`
 	TotalSellQuantity     int     `json:"totalSellQuantity"`
-	BidQty                int     `json:"bidQty"`
-	BidPrice              float64 `json:"bidprice"`
-	AskQty                int     `json:"askQty"`
-	AskPrice              float64 `json:"askPrice"`
+	BidQty                int     `json:"buyQuantity1"`  // Best buy quantity
+	BidPrice              float64 `json:"buyPrice1"`     // Best buy price
+	AskQty                int     `json:"sellQuantity1"` // Best sell quantity
+	AskPrice              float64 `json:"sellPrice1"`    // Best sell price
 	UnderlyingValue       float64 `json:"underlyingValue"`
 }
 
@@ -32,34 +37,209 @@ type OptionData struct {
 }
 
 type Records struct {
-	ExpiryDates     []string     `json:"expiryDates"`
-	Data            []OptionData `json:"data"`
-	TimeStamp       string       `json:"timestamp"`
-	UnderlyingValue float64      `json:"underlyingValue"`
+	ExpiryDates     []string      `json:"expiryDates"`
+	Data            []OptionData  `json:"data"`
+	TimeStamp       string        `json:"timestamp"`
+	UnderlyingValue float64       `json:"underlyingValue"`
+	StrikePrices    StrikePrices  `json:"strikePrices,omitempty"`
+	Totals          []ChainTotals `json:"totals,omitempty"` // Per-expiry totals, filled in by the fetcher
 }
 
 type OptionChain struct {
-	Records Records `json:"records"`
+	Records  Records  `json:"records"`
+	Filtered Filtered `json:"filtered"`
+}
+
+// Filtered is the "filtered" block of an option-chain-v3 response. Only
+// the chain-level totals are decoded; its data rows repeat Records.Data.
+type Filtered struct {
+	CE SideTotals `json:"CE"`
+	PE SideTotals `json:"PE"`
+}
+
+type SideTotals struct {
+	TotalOI     float64 `json:"totOI"`
+	TotalVolume float64 `json:"totVol"`
+}
+
+// ChainTotals are NSE's whole-chain totals for one expiry. option-chain-v3
+// is requested per expiry, so each call's "filtered" block becomes one
+// ChainTotals.
+type ChainTotals struct {
+	ExpiryDate    string  `json:"expiryDate"`
+	CETotalOI     float64 `json:"ceTotOI"`
+	CETotalVolume float64 `json:"ceTotVol"`
+	PETotalOI     float64 `json:"peTotOI"`
+	PETotalVolume float64 `json:"peTotVol"`
+}
+
+// StrikePrices decodes NSE's strikePrices list, which it sends as strings
+// ("24100"), while also accepting plain numbers as written to the stream.
+type StrikePrices []float64
+
+func (sp *StrikePrices) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	out := make(StrikePrices, 0, len(raw))
+	for _, r := range raw {
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			s = string(r)
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid strike price %s: %w", r, err)
+		}
+		out = append(out, v)
+	}
+	*sp = out
+	return nil
 }
 
 type ResponsePayload struct {
+	Symbol                           string    `json:"symbol"`     // Underlying symbol, e.g. NIFTY
 	Timestamp                        time.Time `json:"timestamp"`  // Snapshot timestamp
 	ExpiryDate                       time.Time `json:"expiryDate"` // Expiry date of the option
 	StrikePrice                      float64   `json:"strikePrice"`
-	UnderlyingValue                  float64   `json:"underlyingValue"` // Current underlying (NIFTY) value
+	UnderlyingValue                  float64   `json:"underlyingValue"` // Current underlying value
 	CEOpenInterest                   float64   `json:"ceOpenInterest"`
 	CEChangeInOpenInterestPercentage float64   `json:"ceOpenInterestPercentage"`
 	CEChangeInOpenInterest           float64   `json:"ceChangeInOpenInterest"`
 	CETotalTradedVolume              int       `json:"ceTotalTradedVolume"`
 	CEImpliedVolatility              float64   `json:"ceImpliedVolatility"`
 	CELastPrice                      float64   `json:"ceLastPrice"`
+	CEBidPrice                       float64   `json:"ceBidPrice"`
+	CEBidQty                         int       `json:"ceBidQty"`
+	CEAskPrice                       float64   `json:"ceAskPrice"`
+	CEAskQty                         int       `json:"ceAskQty"`
 	PEOpenInterest                   float64   `json:"peOpenInterest"`
 	PEChangeInOpenInterestPercentage float64   `json:"peOpenInterestPercentage"`
 	PEChangeInOpenInterest           float64   `json:"peChangeInOpenInterest"`
 	PETotalTradedVolume              int       `json:"peTotalTradedVolume"`
 	PEImpliedVolatility              float64   `json:"peImpliedVolatility"`
 	PELastPrice                      float64   `json:"peLastPrice"`
-	IntraDayPCR                      float64   `json:"intraDayPCR"` // Change in PE OI / Change in CE OI
-	PCR                              float64   `json:"pcr"`         // Total PE OI / Total CE OI
+	PEBidPrice                       float64   `json:"peBidPrice"`
+	PEBidQty                         int       `json:"peBidQty"`
+	PEAskPrice                       float64   `json:"peAskPrice"`
+	PEAskQty                         int       `json:"peAskQty"`
+	IntraDayPCR                      *float64  `json:"intraDayPCR"` // Change in PE OI / Change in CE OI, nil when undefined
+	PCR                              *float64  `json:"pcr"`         // Total PE OI / Total CE OI, nil when undefined
+
+	// Position relative to the expiry's ATM strike in the same snapshot.
+	ATMStrike      float64   `json:"atmStrike"`
+	StrikesFromATM int       `json:"strikesFromAtm"` // Listed strikes above (+) or below (-) ATM
+	CEMoneyness    Moneyness `json:"ceMoneyness"`
+	PEMoneyness    Moneyness `json:"peMoneyness"`
+
+	// Price change since the previous close, as NSE reports it.
+	CEPriceChange float64 `json:"cePriceChange"`
+	PEPriceChange float64 `json:"pePriceChange"`
+	// Price against OI change since the previous close, and since the
+	// previous snapshot. Empty when either didn't move or there is no
+	// previous snapshot.
+	CEBuildup         Buildup `json:"ceBuildup"`
+	PEBuildup         Buildup `json:"peBuildup"`
+	CEIntervalBuildup Buildup `json:"ceIntervalBuildup"`
+	PEIntervalBuildup Buildup `json:"peIntervalBuildup"`
+
+	// Moves since the previous snapshot, nil on the day's first, and over
+	// each rolling window the day's snapshots reach back to, keyed by the
+	// window such as "15m".
+	Interval *IntervalDelta           `json:"interval"`
+	Windows  map[string]IntervalDelta `json:"windows"`
+
+	// Whether the IV was solved from the option's price because NSE
+	// reported none, rather than taken from NSE.
+	CEIVComputed bool `json:"ceIvComputed"`
+	PEIVComputed bool `json:"peIvComputed"`
+
+	// Greeks from the IV, nil when the option has no IV or has expired.
+	// Theta is per calendar day, Vega per volatility point, Rho per 1%.
+	CEDelta *float64 `json:"ceDelta"`
+	CEGamma *float64 `json:"ceGamma"`
+	CETheta *float64 `json:"ceTheta"`
+	CEVega  *float64 `json:"ceVega"`
+	CERho   *float64 `json:"ceRho"`
+	PEDelta *float64 `json:"peDelta"`
+	PEGamma *float64 `json:"peGamma"`
+	PETheta *float64 `json:"peTheta"`
+	PEVega  *float64 `json:"peVega"`
+	PERho   *float64 `json:"peRho"`
+}
+
+// Moneyness is where an option's strike sits relative to the underlying.
+type Moneyness string
+
+const (
+	ITM Moneyness = "ITM"
+	ATM Moneyness = "ATM"
+	OTM Moneyness = "OTM"
+)
+
+// IntervalDelta is how far a contract's OI, volume, LTP and IV moved
+// between an earlier snapshot, taken at Since, and the current one. The IV
+// deltas are nil when either snapshot has no IV for that side.
+type IntervalDelta struct {
+	Since    time.Time `json:"since"`
+	CEOI     float64   `json:"ceOI"`
+	CEVolume int       `json:"ceVolume"`
+	CELTP    float64   `json:"ceLTP"`
+	CEIV     *float64  `json:"ceIV"`
+	PEOI     float64   `json:"peOI"`
+	PEVolume int       `json:"peVolume"`
+	PELTP    float64   `json:"peLTP"`
+	PEIV     *float64  `json:"peIV"`
+}
+
+// Buildup classifies a contract's price change against its OI change.
+type Buildup string
+
+const (
+	LongBuildup   Buildup = "long_buildup"   // Price up, OI up
+	ShortBuildup  Buildup = "short_buildup"  // Price down, OI up
+	LongUnwinding Buildup = "long_unwinding" // Price down, OI down
+	ShortCovering Buildup = "short_covering" // Price up, OI down
+)
+
+// ChainSummary is the per-snapshot, per-expiry summary built from NSE's
+// chain-level totals, so the whole-chain ratios don't have to be
+// recomputed by summing strikes.
+type ChainSummary struct {
+	Symbol          string    `json:"symbol"`
+	Timestamp       time.Time `json:"timestamp"`
+	ExpiryDate      time.Time `json:"expiryDate"`
+	UnderlyingValue float64   `json:"underlyingValue"`
+	CETotalOI       float64   `json:"ceTotalOI"`
+	CETotalVolume   float64   `json:"ceTotalVolume"`
+	PETotalOI       float64   `json:"peTotalOI"`
+	PETotalVolume   float64   `json:"peTotalVolume"`
+	PCR             *float64  `json:"pcr"`       // PE total OI / CE total OI, nil when undefined
+	VolumePCR       *float64  `json:"volumePCR"` // PE total volume / CE total volume, nil when undefined
+	StrikePrices    []float64 `json:"strikePrices"`
+}
 
+// ChainAggregate is the per-snapshot, per-expiry aggregate the processor
+// computes from the captured strikes, including the ratios restricted to
+// the strikes nearest the money.
+type ChainAggregate struct {
+	Symbol          string    `json:"symbol"`
+	Timestamp       time.Time `json:"timestamp"`
+	ExpiryDate      time.Time `json:"expiryDate"`
+	UnderlyingValue float64   `json:"underlyingValue"`
+	ATMStrike       float64   `json:"atmStrike"` // Strike closest to the underlying
+	CETotalOI       float64   `json:"ceTotalOI"`
+	PETotalOI       float64   `json:"peTotalOI"`
+	CETotalChangeOI float64   `json:"ceTotalChangeOI"`
+	PETotalChangeOI float64   `json:"peTotalChangeOI"`
+	CETotalVolume   int       `json:"ceTotalVolume"`
+	PETotalVolume   int       `json:"peTotalVolume"`
+	PCR             *float64  `json:"pcr"`           // PE OI / CE OI, nil when undefined
+	ChangeInOIPCR   *float64  `json:"changeInOIPCR"` // PE change in OI / CE change in OI, nil when undefined
+	VolumePCR       *float64  `json:"volumePCR"`     // PE volume / CE volume, nil when undefined
+	ATMWindow       int       `json:"atmWindow"`     // Strikes each side of ATM in the window
+	ATMWindowPCR    *float64  `json:"atmWindowPCR"`  // PE OI / CE OI within the window, nil when undefined
+	MaxPain         *float64  `json:"maxPain"`       // Strike where option writers pay out least at expiry, nil without OI
 }
@@ -0,0 +1,116 @@
+package processing
+
+import (
+	"math"
+	"server/internal/models"
+	"slices"
+	"time"
+)
+
+// DefaultATMWindow is how many strikes either side of the ATM strike the
+// window PCR covers when ProcessingService.ATMWindow is zero.
+const DefaultATMWindow = 5
+
+// aggregateChain computes one ChainAggregate per expiry from a snapshot's
+// rows, in the order the expiries first appear. window is the number of
+// strikes either side of ATM the window PCR covers.
+func aggregateChain(rows []models.ResponsePayload, window int) []models.ChainAggregate {
+	var order []time.Time
+	byExpiry := map[time.Time][]models.ResponsePayload{}
+	for _, row := range rows {
+		if _, ok := byExpiry[row.ExpiryDate]; !ok {
+			order = append(order, row.ExpiryDate)
+		}
+		byExpiry[row.ExpiryDate] = append(byExpiry[row.ExpiryDate], row)
+	}
+
+	aggregates := make([]models.ChainAggregate, 0, len(order))
+	for _, expiry := range order {
+		expiryRows := byExpiry[expiry]
+		first := expiryRows[0]
+
+		agg := models.ChainAggregate{
+			Symbol:          first.Symbol,
+			Timestamp:       first.Timestamp,
+			ExpiryDate:      expiry,
+			UnderlyingValue: first.UnderlyingValue,
+			ATMWindow:       window,
+		}
+		for _, row := range expiryRows {
+			agg.CETotalOI += row.CEOpenInterest
+			agg.PETotalOI += row.PEOpenInterest
+			agg.CETotalChangeOI += row.CEChangeInOpenInterest
+			agg.PETotalChangeOI += row.PEChangeInOpenInterest
+			agg.CETotalVolume += row.CETotalTradedVolume
+			agg.PETotalVolume += row.PETotalTradedVolume
+		}
+		agg.PCR = calculatePCR(agg.PETotalOI, agg.CETotalOI)
+		agg.ChangeInOIPCR = calculatePCR(agg.PETotalChangeOI, agg.CETotalChangeOI)
+		agg.VolumePCR = calculatePCR(float64(agg.PETotalVolume), float64(agg.CETotalVolume))
+
+		sorted := slices.Clone(expiryRows)
+		slices.SortFunc(sorted, func(a, b models.ResponsePayload) int {
+			switch {
+			case a.StrikePrice < b.StrikePrice:
+				return -1
+			case a.StrikePrice > b.StrikePrice:
+				return 1
+			}
+			return 0
+		})
+
+		atm := atmIndex(sorted, first.UnderlyingValue)
+		agg.ATMStrike = sorted[atm].StrikePrice
+
+		var windowCE, windowPE float64
+		for _, row := range sorted[max(0, atm-window):min(len(sorted), atm+window+1)] {
+			windowCE += row.CEOpenInterest
+			windowPE += row.PEOpenInterest
+		}
+		agg.ATMWindowPCR = calculatePCR(windowPE, windowCE)
+		agg.MaxPain = maxPain(sorted)
+
+		aggregates = append(aggregates, agg)
+	}
+	return aggregates
+}
+
+// maxPain returns the strike, among rows sorted by strike, at which the
+// options open across the chain would be worth least to their holders if
+// the underlying expired there. Ties go to the lower strike. It returns nil
+// when the expiry has no open interest.
+func maxPain(sorted []models.ResponsePayload) *float64 {
+	var totalOI float64
+	for _, row := range sorted {
+		totalOI += row.CEOpenInterest + row.PEOpenInterest
+	}
+	if totalOI == 0 {
+		return nil
+	}
+
+	best, bestPain := 0, math.Inf(1)
+	for i, settle := range sorted {
+		var pain float64
+		for _, row := range sorted {
+			pain += row.CEOpenInterest * max(0, settle.StrikePrice-row.StrikePrice)
+			pain += row.PEOpenInterest * max(0, row.StrikePrice-settle.StrikePrice)
+		}
+		if pain < bestPain {
+			best, bestPain = i, pain
+		}
+	}
+	strike := sorted[best].StrikePrice
+	return &strike
+}
+
+// atmIndex returns the index of the strike closest to underlying in rows
+// sorted by strike, preferring the lower strike on a tie.
+func atmIndex(sorted []models.ResponsePayload, underlying float64) int {
+	best := 0
+	for i, row := range sorted {
+		if math.Abs(row.StrikePrice-underlying) < math.Abs(sorted[best].StrikePrice-underlying) {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,64 @@
+package processing
+
+import (
+	"server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestAggregateChain(t *testing.T) {
+	near := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC)
+	far := time.Date(2026, 7, 30, 0, 0, 0, 0, time.UTC)
+
+	row := func(expiry time.Time, strike, ceOI, peOI float64) models.ResponsePayload {
+		return models.ResponsePayload{
+			Symbol: "NIFTY", ExpiryDate: expiry, StrikePrice: strike, UnderlyingValue: 24281.4,
+			CEOpenInterest: ceOI, PEOpenInterest: peOI,
+			CEChangeInOpenInterest: ceOI / 10, PEChangeInOpenInterest: -peOI / 10,
+			CETotalTradedVolume: int(ceOI), PETotalTradedVolume: int(peOI) * 2,
+		}
+	}
+	rows := []models.ResponsePayload{
+		row(near, 24400, 10, 0),
+		row(near, 24200, 40, 60),
+		row(near, 24300, 20, 30),
+		row(near, 24250, 30, 50),
+		row(far, 24300, 0, 5),
+	}
+
+	aggregates := aggregateChain(rows, 1)
+	if len(aggregates) != 2 {
+		t.Fatalf("got %d aggregates, want one per expiry", len(aggregates))
+	}
+
+	got := aggregates[0]
+	if !got.ExpiryDate.Equal(near) {
+		t.Errorf("first aggregate is for %v, want the first listed expiry", got.ExpiryDate)
+	}
+	if got.ATMStrike != 24300 {
+		t.Errorf("ATM strike = %v, want 24300", got.ATMStrike)
+	}
+	if got.CETotalOI != 100 || got.PETotalOI != 140 {
+		t.Errorf("totals = %v/%v, want 100/140", got.CETotalOI, got.PETotalOI)
+	}
+	if got.PCR == nil || *got.PCR != 1.4 {
+		t.Errorf("PCR = %v, want 1.4", got.PCR)
+	}
+	if got.VolumePCR == nil || *got.VolumePCR != 2.8 {
+		t.Errorf("volume PCR = %v, want 2.8", got.VolumePCR)
+	}
+	// One strike either side of 24300 is 24250..24400: PE 80 / CE 60.
+	if got.ATMWindowPCR == nil || *got.ATMWindowPCR != 80.0/60.0 {
+		t.Errorf("ATM window PCR = %v, want %v", got.ATMWindowPCR, 80.0/60.0)
+	}
+
+	// Settling at 24250 costs writers 40*50 on calls and 30*50 on puts,
+	// less than at any other strike.
+	if got.MaxPain == nil || *got.MaxPain != 24250 {
+		t.Errorf("max pain = %v, want 24250", got.MaxPain)
+	}
+
+	if aggregates[1].PCR != nil {
+		t.Errorf("PCR with no call OI = %v, want nil", *aggregates[1].PCR)
+	}
+}
@@ -0,0 +1,92 @@
+package processing
+
+import (
+	"fmt"
+	"server/internal/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// classifyBuildup reads a price change against an OI change. It returns
+// "" when either is flat, since neither side is then being built or
+// unwound.
+func classifyBuildup(priceChange, oiChange float64) models.Buildup {
+	switch {
+	case priceChange > 0 && oiChange > 0:
+		return models.LongBuildup
+	case priceChange < 0 && oiChange > 0:
+		return models.ShortBuildup
+	case priceChange < 0 && oiChange < 0:
+		return models.LongUnwinding
+	case priceChange > 0 && oiChange < 0:
+		return models.ShortCovering
+	}
+	return ""
+}
+
+// tagIntervalBuildup classifies each row against the same contract in
+// previous, the snapshot before it. Rows are left unclassified when
+// previous is not older than them, as with a redelivered entry, or lacks
+// the contract.
+func tagIntervalBuildup(rows, previous []models.ResponsePayload) {
+	type contract struct {
+		expiry time.Time
+		strike float64
+	}
+	before := make(map[contract]models.ResponsePayload, len(previous))
+	for _, p := range previous {
+		before[contract{p.ExpiryDate, p.StrikePrice}] = p
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		prev, ok := before[contract{row.ExpiryDate, row.StrikePrice}]
+		if !ok || !prev.Timestamp.Before(row.Timestamp) {
+			continue
+		}
+		row.CEIntervalBuildup = classifyBuildup(row.CELastPrice-prev.CELastPrice, row.CEOpenInterest-prev.CEOpenInterest)
+		row.PEIntervalBuildup = classifyBuildup(row.PELastPrice-prev.PELastPrice, row.PEOpenInterest-prev.PEOpenInterest)
+	}
+}
+
+// ParseBuildups parses a comma-separated list of buildup names, e.g.
+// "long_buildup,short_covering".
+func ParseBuildups(spec string) ([]models.Buildup, error) {
+	known := []models.Buildup{models.LongBuildup, models.ShortBuildup, models.LongUnwinding, models.ShortCovering}
+
+	var buildups []models.Buildup
+	for _, part := range strings.Split(spec, ",") {
+		b := models.Buildup(strings.ToLower(strings.TrimSpace(part)))
+		if b == "" {
+			continue
+		}
+		if !slices.Contains(known, b) {
+			return nil, fmt.Errorf("unknown buildup %q, want one of %v", b, known)
+		}
+		buildups = append(buildups, b)
+	}
+	return buildups, nil
+}
+
+// WithBuildup returns the rows whose CE or PE side matches one of
+// sinceClose, the buildups since the previous close, and one of
+// sinceSnapshot, the buildups since the previous snapshot. An empty list
+// matches every row.
+func WithBuildup(rows []models.ResponsePayload, sinceClose, sinceSnapshot []models.Buildup) []models.ResponsePayload {
+	if len(sinceClose) == 0 && len(sinceSnapshot) == 0 {
+		return rows
+	}
+	matches := func(want []models.Buildup, ce, pe models.Buildup) bool {
+		return len(want) == 0 || slices.Contains(want, ce) || slices.Contains(want, pe)
+	}
+
+	kept := make([]models.ResponsePayload, 0, len(rows))
+	for _, row := range rows {
+		if matches(sinceClose, row.CEBuildup, row.PEBuildup) &&
+			matches(sinceSnapshot, row.CEIntervalBuildup, row.PEIntervalBuildup) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
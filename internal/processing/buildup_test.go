@@ -0,0 +1,63 @@
+package processing
+
+import (
+	"server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestClassifyBuildup(t *testing.T) {
+	for _, tc := range []struct {
+		price, oi float64
+		want      models.Buildup
+	}{
+		{2.5, 1500, models.LongBuildup},
+		{-2.5, 1500, models.ShortBuildup},
+		{-2.5, -1500, models.LongUnwinding},
+		{2.5, -1500, models.ShortCovering},
+		{0, 1500, ""},
+		{2.5, 0, ""},
+	} {
+		if got := classifyBuildup(tc.price, tc.oi); got != tc.want {
+			t.Errorf("classifyBuildup(%v, %v) = %q, want %q", tc.price, tc.oi, got, tc.want)
+		}
+	}
+}
+
+func TestTagIntervalBuildup(t *testing.T) {
+	expiry := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC)
+	earlier := time.Date(2026, 7, 17, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(3 * time.Minute)
+
+	row := func(ts time.Time, strike, ceLTP, ceOI, peLTP, peOI float64) models.ResponsePayload {
+		return models.ResponsePayload{
+			Timestamp: ts, ExpiryDate: expiry, StrikePrice: strike,
+			CELastPrice: ceLTP, CEOpenInterest: ceOI, PELastPrice: peLTP, PEOpenInterest: peOI,
+		}
+	}
+	previous := []models.ResponsePayload{row(earlier, 24300, 100, 1000, 80, 2000)}
+	rows := []models.ResponsePayload{
+		row(later, 24300, 110, 1200, 70, 1800),
+		row(later, 24350, 60, 500, 120, 700),
+	}
+
+	tagIntervalBuildup(rows, previous)
+	if rows[0].CEIntervalBuildup != models.LongBuildup || rows[0].PEIntervalBuildup != models.LongUnwinding {
+		t.Errorf("24300 = %q/%q, want long_buildup/long_unwinding", rows[0].CEIntervalBuildup, rows[0].PEIntervalBuildup)
+	}
+	if rows[1].CEIntervalBuildup != "" || rows[1].PEIntervalBuildup != "" {
+		t.Errorf("strike missing from previous snapshot classified as %q/%q", rows[1].CEIntervalBuildup, rows[1].PEIntervalBuildup)
+	}
+
+	// A redelivered older snapshot is not compared against a newer one.
+	stale := []models.ResponsePayload{row(earlier, 24300, 90, 900, 90, 2100)}
+	tagIntervalBuildup(stale, rows)
+	if stale[0].CEIntervalBuildup != "" {
+		t.Errorf("older row classified against newer snapshot as %q", stale[0].CEIntervalBuildup)
+	}
+
+	filtered := WithBuildup(rows, nil, []models.Buildup{models.LongUnwinding})
+	if len(filtered) != 1 || filtered[0].StrikePrice != 24300 {
+		t.Errorf("filter by long_unwinding kept %v, want only 24300", filtered)
+	}
+}
@@ -0,0 +1,168 @@
+package processing
+
+import (
+	"server/internal/models"
+	"sync"
+	"time"
+)
+
+// Snapshot is everything the processor derives from one stream entry.
+type Snapshot struct {
+	Rows       []models.ResponsePayload
+	Summaries  []models.ChainSummary
+	Aggregates []models.ChainAggregate
+}
+
+// Day holds the processed rows for one symbol's current trading day. It is
+// appended to by ProcessingOptionChain and read concurrently by the HTTP
+// handlers, so all access goes through its lock.
+type Day struct {
+	mu         sync.RWMutex
+	records    []models.ResponsePayload
+	summaries  []models.ChainSummary
+	aggregates []models.ChainAggregate
+	// applied holds the stream entry IDs already added, so an entry
+	// redelivered after a failed ack is not counted twice.
+	applied map[string]struct{}
+}
+
+func NewDay() *Day {
+	return &Day{
+		records:    []models.ResponsePayload{},
+		summaries:  []models.ChainSummary{},
+		aggregates: []models.ChainAggregate{},
+		applied:    map[string]struct{}{},
+	}
+}
+
+// Apply adds the snapshot decoded from stream entry id, unless that entry
+// was already applied. It reports whether anything was added.
+func (d *Day) Apply(id string, snap Snapshot) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.applied[id]; ok {
+		return false
+	}
+	d.applied[id] = struct{}{}
+	d.append(snap)
+	return true
+}
+
+// Applied reports whether stream entry id has already been applied.
+func (d *Day) Applied(id string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.applied[id]
+	return ok
+}
+
+// Append adds a processed snapshot to the day.
+func (d *Day) Append(snap Snapshot) {
+	d.mu.Lock()
+	d.append(snap)
+	d.mu.Unlock()
+}
+
+func (d *Day) append(snap Snapshot) {
+	d.records = append(d.records, snap.Rows...)
+	d.summaries = append(d.summaries, snap.Summaries...)
+	d.aggregates = append(d.aggregates, snap.Aggregates...)
+}
+
+// MarkApplied records stream entry IDs as applied without adding rows,
+// for entries whose rows reached the day another way.
+func (d *Day) MarkApplied(ids ...string) {
+	d.mu.Lock()
+	for _, id := range ids {
+		d.applied[id] = struct{}{}
+	}
+	d.mu.Unlock()
+}
+
+// Reset clears the day, used when a new trading day starts.
+func (d *Day) Reset() {
+	d.mu.Lock()
+	d.records = []models.ResponsePayload{}
+	d.summaries = []models.ChainSummary{}
+	d.aggregates = []models.ChainAggregate{}
+	d.applied = map[string]struct{}{}
+	d.mu.Unlock()
+}
+
+// Len returns the number of rows held for the day.
+func (d *Day) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.records)
+}
+
+// Records returns a copy of the day's rows, safe to use without holding
+// the lock.
+func (d *Day) Records() []models.ResponsePayload {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]models.ResponsePayload, len(d.records))
+	copy(out, d.records)
+	return out
+}
+
+// Latest returns a copy of the rows of the most recent snapshot added, or
+// nil if the day is empty.
+func (d *Day) Latest() []models.ResponsePayload {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if len(d.records) == 0 {
+		return nil
+	}
+	last := d.records[len(d.records)-1].Timestamp
+	start := len(d.records) - 1
+	for start > 0 && d.records[start-1].Timestamp.Equal(last) {
+		start--
+	}
+	out := make([]models.ResponsePayload, len(d.records)-start)
+	copy(out, d.records[start:])
+	return out
+}
+
+// At returns a copy of the rows of the latest snapshot taken at or before
+// t, or nil if there is none.
+func (d *Day) At(t time.Time) []models.ResponsePayload {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var at time.Time
+	for _, r := range d.records {
+		if !r.Timestamp.After(t) && r.Timestamp.After(at) {
+			at = r.Timestamp
+		}
+	}
+	if at.IsZero() {
+		return nil
+	}
+
+	var out []models.ResponsePayload
+	for _, r := range d.records {
+		if r.Timestamp.Equal(at) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Summaries returns a copy of the day's chain summaries.
+func (d *Day) Summaries() []models.ChainSummary {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]models.ChainSummary, len(d.summaries))
+	copy(out, d.summaries)
+	return out
+}
+
+// Aggregates returns a copy of the day's chain aggregates.
+func (d *Day) Aggregates() []models.ChainAggregate {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]models.ChainAggregate, len(d.aggregates))
+	copy(out, d.aggregates)
+	return out
+}
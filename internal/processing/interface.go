@@ -2,18 +2,58 @@ package processing
 
 import (
 	"context"
+	"server/internal/db"
 	"server/internal/models"
+	"time"
 )
 
+// StreamEntry is one snapshot read from the stream, with the entry ID used
+// to acknowledge it. Err is set when the entry's data could not be
+// decoded; such entries are acknowledged and skipped.
+type StreamEntry struct {
+	ID      string
+	Records models.Records
+	Err     error
+}
+
 type Reader interface {
-	ReadStream(ctx context.Context) ([]models.Records, error)
-	ReadLatest(ctx context.Context) (models.Records, error)
+	ReadStream(ctx context.Context) ([]StreamEntry, error)
+	// ReadGroup blocks up to block for new entries for this consumer.
+	ReadGroup(ctx context.Context, block time.Duration) ([]StreamEntry, error)
+	// Claim returns delivered but unacknowledged entries, including ones
+	// another consumer has left idle for longer than minIdle.
+	Claim(ctx context.Context, minIdle time.Duration) ([]StreamEntry, error)
+	Ack(ctx context.Context, ids ...string) error
 }
 
 type DBWriter interface {
 	WriteToDB(ctx context.Context, records *[]models.ResponsePayload) error
+	WriteSummaries(ctx context.Context, summaries []models.ChainSummary) error
+	WriteAggregates(ctx context.Context, aggregates []models.ChainAggregate) error
+}
+
+// DBReader reads back rows already persisted for a symbol, used to rebuild
+// the day after a restart.
+type DBReader interface {
+	ReadDay(ctx context.Context, symbol string, from, to time.Time) ([]models.ResponsePayload, error)
+	ReadSummaries(ctx context.Context, symbol string, from, to time.Time) ([]models.ChainSummary, error)
+	ReadAggregates(ctx context.Context, symbol string, from, to time.Time) ([]models.ChainAggregate, error)
 }
 
 type CSVUploader interface {
 	Upload(ctx context.Context, key string, data []byte) error
 }
+
+// PartitionStore manages the daily partitions of the snapshot table.
+type PartitionStore interface {
+	EnsurePartitions(ctx context.Context, from time.Time, days int) ([]string, error)
+	Partitions(ctx context.Context, loc *time.Location) ([]db.Partition, error)
+	PartitionSymbols(ctx context.Context, name string) (map[string]int64, error)
+	DefaultPartitionRows(ctx context.Context) (int64, error)
+	RemovePartition(ctx context.Context, name string, detachOnly bool) error
+}
+
+// ArchiveChecker looks up objects in the bucket daily CSVs are uploaded to.
+type ArchiveChecker interface {
+	ObjectSize(ctx context.Context, key string) (int64, error)
+}
@@ -0,0 +1,97 @@
+package processing
+
+import (
+	"fmt"
+	"server/internal/models"
+	"time"
+)
+
+// RollingWindows are the look-backs, beyond the previous snapshot, that
+// each row's interval deltas are computed over.
+var RollingWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, 30 * time.Minute, 60 * time.Minute}
+
+// tagIntervalDeltas sets each row's moves since the same contract in
+// previous, the snapshot before it, and since the contract in each of
+// windows, keyed by window label. A window's snapshot is the latest taken
+// at least that long before the row, so its delta can span a little more
+// than the window when snapshots don't line up with it.
+func tagIntervalDeltas(rows, previous []models.ResponsePayload, windows map[string][]models.ResponsePayload) {
+	type contract struct {
+		expiry time.Time
+		strike float64
+	}
+	index := func(snapshot []models.ResponsePayload) map[contract]models.ResponsePayload {
+		byContract := make(map[contract]models.ResponsePayload, len(snapshot))
+		for _, p := range snapshot {
+			byContract[contract{p.ExpiryDate, p.StrikePrice}] = p
+		}
+		return byContract
+	}
+
+	before := index(previous)
+	windowBefore := make(map[string]map[contract]models.ResponsePayload, len(windows))
+	for label, snapshot := range windows {
+		windowBefore[label] = index(snapshot)
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		key := contract{row.ExpiryDate, row.StrikePrice}
+
+		if prev, ok := before[key]; ok && prev.Timestamp.Before(row.Timestamp) {
+			delta := intervalDelta(*row, prev)
+			row.Interval = &delta
+		}
+		for label, byContract := range windowBefore {
+			base, ok := byContract[key]
+			if !ok || !base.Timestamp.Before(row.Timestamp) {
+				continue
+			}
+			if row.Windows == nil {
+				row.Windows = make(map[string]models.IntervalDelta, len(windowBefore))
+			}
+			row.Windows[label] = intervalDelta(*row, base)
+		}
+	}
+}
+
+// rollingSnapshots returns, for each rolling window, the day's latest
+// snapshot taken at least that window before at. Windows the day doesn't
+// reach back to are left out.
+func rollingSnapshots(day *Day, at time.Time) map[string][]models.ResponsePayload {
+	snapshots := make(map[string][]models.ResponsePayload, len(RollingWindows))
+	for _, window := range RollingWindows {
+		if snapshot := day.At(at.Add(-window)); snapshot != nil {
+			snapshots[windowLabel(window)] = snapshot
+		}
+	}
+	return snapshots
+}
+
+// windowLabel names a window in whole minutes, e.g. "15m".
+func windowLabel(window time.Duration) string {
+	return fmt.Sprintf("%dm", int(window.Minutes()))
+}
+
+func intervalDelta(row, base models.ResponsePayload) models.IntervalDelta {
+	return models.IntervalDelta{
+		Since:    base.Timestamp,
+		CEOI:     row.CEOpenInterest - base.CEOpenInterest,
+		CEVolume: row.CETotalTradedVolume - base.CETotalTradedVolume,
+		CELTP:    row.CELastPrice - base.CELastPrice,
+		CEIV:     ivDelta(row.CEImpliedVolatility, base.CEImpliedVolatility),
+		PEOI:     row.PEOpenInterest - base.PEOpenInterest,
+		PEVolume: row.PETotalTradedVolume - base.PETotalTradedVolume,
+		PELTP:    row.PELastPrice - base.PELastPrice,
+		PEIV:     ivDelta(row.PEImpliedVolatility, base.PEImpliedVolatility),
+	}
+}
+
+// ivDelta returns the change in IV, or nil when either side has none.
+func ivDelta(iv, base float64) *float64 {
+	if iv <= 0 || base <= 0 {
+		return nil
+	}
+	d := iv - base
+	return &d
+}
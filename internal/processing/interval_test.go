@@ -0,0 +1,52 @@
+package processing
+
+import (
+	"server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTagIntervalDeltas(t *testing.T) {
+	expiry := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC)
+	open := time.Date(2026, 7, 17, 9, 15, 0, 0, time.UTC)
+
+	snapshot := func(minutes int, ceOI float64, ceVol int, ceIV float64) []models.ResponsePayload {
+		return []models.ResponsePayload{{
+			Timestamp: open.Add(time.Duration(minutes) * time.Minute), ExpiryDate: expiry, StrikePrice: 24300,
+			CEOpenInterest: ceOI, CETotalTradedVolume: ceVol, CELastPrice: 100 + float64(minutes), CEImpliedVolatility: ceIV,
+		}}
+	}
+
+	// Snapshots every 3 minutes for the first 18 minutes of the session.
+	day := NewDay()
+	for m := 0; m <= 15; m += 3 {
+		day.Append(Snapshot{Rows: snapshot(m, 1000+float64(m)*10, m*100, 12)})
+	}
+
+	rows := snapshot(18, 1300, 2000, 0)
+	tagIntervalDeltas(rows, day.Latest(), rollingSnapshots(day, rows[0].Timestamp))
+	got := rows[0]
+
+	if got.Interval == nil {
+		t.Fatal("no delta against the previous snapshot")
+	}
+	if !got.Interval.Since.Equal(open.Add(15*time.Minute)) || got.Interval.CEOI != 150 ||
+		got.Interval.CEVolume != 500 || got.Interval.CELTP != 3 {
+		t.Errorf("interval delta = %+v, want since 09:30 with OI 150, volume 500, LTP 3", *got.Interval)
+	}
+	if got.Interval.CEIV != nil {
+		t.Errorf("IV delta = %v without a current IV, want nil", *got.Interval.CEIV)
+	}
+
+	// 5m looks back to 09:28 or earlier, which is the 09:27 snapshot; 15m
+	// to 09:18. The session doesn't reach back 30m or 60m.
+	if w, ok := got.Windows["5m"]; !ok || !w.Since.Equal(open.Add(12*time.Minute)) || w.CEOI != 180 {
+		t.Errorf("5m window = %+v, want since 09:27 with OI 180", w)
+	}
+	if w, ok := got.Windows["15m"]; !ok || !w.Since.Equal(open.Add(3*time.Minute)) || w.CEVolume != 1700 {
+		t.Errorf("15m window = %+v, want since 09:18 with volume 1700", w)
+	}
+	if _, ok := got.Windows["30m"]; ok {
+		t.Error("30m window present before the session is 30 minutes old")
+	}
+}
@@ -0,0 +1,72 @@
+package processing
+
+import (
+	"server/internal/models"
+	"slices"
+	"time"
+)
+
+// tagMoneyness sets each row's ATM strike, its offset from ATM in listed
+// strikes, and the moneyness of both sides. ATM is the listed strike
+// nearest the underlying, taken per snapshot and expiry since each expiry
+// lists its own strikes. Counting listed strikes rather than dividing by a
+// fixed spacing keeps offsets meaningful where the spacing widens away
+// from the money.
+func tagMoneyness(rows []models.ResponsePayload) {
+	type chainKey struct {
+		timestamp int64
+		expiry    time.Time
+	}
+	chains := map[chainKey][]int{}
+	for i, row := range rows {
+		key := chainKey{row.Timestamp.Unix(), row.ExpiryDate}
+		chains[key] = append(chains[key], i)
+	}
+
+	for _, indexes := range chains {
+		slices.SortFunc(indexes, func(a, b int) int {
+			switch {
+			case rows[a].StrikePrice < rows[b].StrikePrice:
+				return -1
+			case rows[a].StrikePrice > rows[b].StrikePrice:
+				return 1
+			}
+			return 0
+		})
+		sorted := make([]models.ResponsePayload, len(indexes))
+		for i, idx := range indexes {
+			sorted[i] = rows[idx]
+		}
+
+		atm := atmIndex(sorted, sorted[0].UnderlyingValue)
+		atmStrike := sorted[atm].StrikePrice
+		for i, idx := range indexes {
+			row := &rows[idx]
+			row.ATMStrike = atmStrike
+			row.StrikesFromATM = i - atm
+			switch {
+			case i == atm:
+				row.CEMoneyness, row.PEMoneyness = models.ATM, models.ATM
+			case i < atm:
+				row.CEMoneyness, row.PEMoneyness = models.ITM, models.OTM
+			default:
+				row.CEMoneyness, row.PEMoneyness = models.OTM, models.ITM
+			}
+		}
+	}
+}
+
+// WithinATM returns the rows at most window listed strikes from their
+// expiry's ATM strike. A negative window returns every row.
+func WithinATM(rows []models.ResponsePayload, window int) []models.ResponsePayload {
+	if window < 0 {
+		return rows
+	}
+	within := make([]models.ResponsePayload, 0, len(rows))
+	for _, row := range rows {
+		if row.StrikesFromATM >= -window && row.StrikesFromATM <= window {
+			within = append(within, row)
+		}
+	}
+	return within
+}
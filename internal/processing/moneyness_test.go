@@ -0,0 +1,60 @@
+package processing
+
+import (
+	"server/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTagMoneynessAndWindow(t *testing.T) {
+	ts := time.Date(2026, 7, 17, 10, 0, 0, 0, time.UTC)
+	near := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC)
+	far := time.Date(2026, 7, 30, 0, 0, 0, 0, time.UTC)
+
+	row := func(expiry time.Time, strike float64) models.ResponsePayload {
+		return models.ResponsePayload{Timestamp: ts, ExpiryDate: expiry, StrikePrice: strike, UnderlyingValue: 24281.4}
+	}
+	// The far expiry lists strikes 100 apart, so its ATM is 24300 too but
+	// one strike away means 24200 or 24400.
+	rows := []models.ResponsePayload{
+		row(near, 24350), row(near, 24200), row(near, 24300), row(near, 24250),
+		row(far, 24100), row(far, 24300), row(far, 24200), row(far, 24400),
+	}
+	tagMoneyness(rows)
+
+	want := map[time.Time]map[float64]struct {
+		offset int
+		ce, pe models.Moneyness
+	}{
+		near: {
+			24200: {-2, models.ITM, models.OTM},
+			24250: {-1, models.ITM, models.OTM},
+			24300: {0, models.ATM, models.ATM},
+			24350: {1, models.OTM, models.ITM},
+		},
+		far: {
+			24100: {-2, models.ITM, models.OTM},
+			24200: {-1, models.ITM, models.OTM},
+			24300: {0, models.ATM, models.ATM},
+			24400: {1, models.OTM, models.ITM},
+		},
+	}
+	for _, r := range rows {
+		w := want[r.ExpiryDate][r.StrikePrice]
+		if r.ATMStrike != 24300 || r.StrikesFromATM != w.offset || r.CEMoneyness != w.ce || r.PEMoneyness != w.pe {
+			t.Errorf("%v %v: got atm=%v offset=%d ce=%s pe=%s, want atm=24300 offset=%d ce=%s pe=%s",
+				r.ExpiryDate.Format("2006-01-02"), r.StrikePrice,
+				r.ATMStrike, r.StrikesFromATM, r.CEMoneyness, r.PEMoneyness, w.offset, w.ce, w.pe)
+		}
+	}
+
+	if got := WithinATM(rows, 1); len(got) != 6 {
+		t.Errorf("window 1 kept %d rows, want 3 per expiry", len(got))
+	}
+	if got := WithinATM(rows, 0); len(got) != 2 {
+		t.Errorf("window 0 kept %d rows, want the ATM row per expiry", len(got))
+	}
+	if got := WithinATM(rows, -1); len(got) != len(rows) {
+		t.Errorf("no window kept %d rows, want all %d", len(got), len(rows))
+	}
+}
@@ -6,21 +6,55 @@ import (
 	"log/slog"
 	"server/internal/csvexport"
 	"server/internal/db"
+	"server/internal/greeks"
+	"server/internal/market"
 	"server/internal/models"
+	"server/internal/symbols"
 	"strings"
-	"sync"
 	"time"
 )
 
 type ProcessingService struct {
+	Symbol   symbols.Symbol
 	Reader   Reader
 	DBWriter DBWriter
+	// DBReader, when set, lets a restarted processor restore the rows it
+	// already persisted and lets the end-of-day check find missing ones.
+	DBReader DBReader
 	Uploader CSVUploader
+	// CSVPrecision sets per-column decimals for the daily CSV; columns
+	// not in it are written in full.
+	CSVPrecision csvexport.Precision
+	// ATMWindow is how many strikes either side of ATM the window PCR
+	// covers. Zero means DefaultATMWindow.
+	ATMWindow int
+	// Rates feed the Greeks computed for every row.
+	Rates    greeks.Rates
+	Schedule *market.Schedule
+
+	buffer *writeBuffer
 }
 
-func (r *ProcessingService) ProcessingOptionChain(ctx context.Context, db *db.DB, mu *sync.RWMutex, logger *slog.Logger, records *[]models.ResponsePayload) error {
+const (
+	// readBlock bounds how long a stream read waits for new entries, which
+	// is also how often the loop re-checks the market phase when idle.
+	readBlock = 30 * time.Second
+	// claimIdle is how long an entry must sit unacknowledged with another
+	// consumer before this one takes it over.
+	claimIdle = time.Minute
+	// readErrorBackoff is the pause after a failed stream read.
+	readErrorBackoff = 10 * time.Second
+)
+
+// ProcessingOptionChain consumes the symbol's stream through its consumer
+// group, adding every snapshot to day as it arrives and queueing it for
+// the database, which acknowledges it once written. After the close it
+// checks the database holds the whole day and uploads the day to the
+// bucket, once per trading day.
+func (r *ProcessingService) ProcessingOptionChain(ctx context.Context, db *db.DB, logger *slog.Logger, day *Day) error {
 	var lastTimeStampRecorded string
-	isWrittenToDB := false
+	var lastStatus string
+	isReconciled := false
 
 	loc, err := time.LoadLocation("Asia/Kolkata")
 	if err != nil {
@@ -28,128 +62,324 @@ func (r *ProcessingService) ProcessingOptionChain(ctx context.Context, db *db.DB
 		return err
 	}
 
-	// Add ticker to prevent tight loop and reduce CPU usage
-	ticker := time.NewTicker(3 * time.Minute)
-	defer ticker.Stop()
+	r.buffer = newWriteBuffer(r.DBWriter, r.Reader.Ack)
+	go r.buffer.Run(ctx, logger)
+
+	// Restore whatever today already produced before a restart, then take
+	// the entries delivered before it but never acknowledged.
+	now := time.Now().In(loc)
+	lastTimeStampRecorded = now.Format("02-Jan-2006")
+	r.rebuildDay(ctx, logger, day, now, loc)
+
+	pending, err := r.Reader.Claim(ctx, claimIdle)
+	if err != nil {
+		logger.Error("Failed to reclaim pending entries", slog.Any("error", err))
+	} else if len(pending) > 0 {
+		logger.Info("Reclaimed pending entries", slog.Int("count", len(pending)))
+		r.applyEntries(ctx, logger, day, pending, time.Now().In(loc), loc)
+	}
+
+	// report logs a status line only when it differs from the previous one,
+	// so idle phases don't log on every read timeout.
+	report := func(msg string, attrs ...any) {
+		if msg != lastStatus {
+			logger.Info(msg, attrs...)
+			lastStatus = msg
+		}
+	}
 
 	for {
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
 			logger.Info("Gracefully shutdown through context cancellation")
 			return nil
-		case <-ticker.C:
-			maxRetries := 10
-			now := time.Now().In(loc)
+		}
 
-			startTime := time.Date(now.Year(), now.Month(), now.Day(), 9, 15, 0, 0, loc)
-			endTime := time.Date(now.Year(), now.Month(), now.Day(), 15, 30, 0, 0, loc)
+		entries, err := r.Reader.ReadGroup(ctx, readBlock)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			logger.Error("Failed to read stream", slog.Any("error", err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(readErrorBackoff):
+			}
+			continue
+		}
 
-			currentDate := now.Format("02-Jan-2006")
+		now := time.Now().In(loc)
+		state := r.Schedule.At(now)
 
-			if lastTimeStampRecorded != currentDate {
-				if lastTimeStampRecorded != "" {
-					mu.Lock()
-					*records = []models.ResponsePayload{}
-					mu.Unlock()
-				}
+		currentDate := now.Format("02-Jan-2006")
 
-				lastTimeStampRecorded = currentDate
-				isWrittenToDB = false
-				logger.Info("New trading day detected. Cleared previous records.")
+		if lastTimeStampRecorded != currentDate {
+			if lastTimeStampRecorded != "" {
+				day.Reset()
 			}
 
-			if now.Before(startTime) {
-				logger.Info("Market not started yet. Waiting for market to open.")
-				continue
-			} else if now.After(endTime) {
-				logger.Info("Market closed. Stopping data fetch.")
-				if !isWrittenToDB {
-					err := r.DBWriter.WriteToDB(ctx, records)
-					if err != nil {
-						logger.Error("Failed to write to database", slog.Any("error", err))
-						continue
-					}
-
-					r.uploadDailyCSV(ctx, mu, logger, records, now)
-
-					isWrittenToDB = true
-				}
-				continue
-			}
+			lastTimeStampRecorded = currentDate
+			isReconciled = false
+			logger.Info("New trading day detected. Cleared previous records.")
+		}
 
-			if isMarketHoliday(now, loc) {
-				logger.Info("Market is closed today. Skipping data fetch.")
-				continue
-			}
+		if len(entries) > 0 {
+			lastStatus = ""
+			r.applyEntries(ctx, logger, day, entries, now, loc)
+			continue
+		}
 
-			var newRecords models.Records
-			var recordFetchError error
-
-			for attempt := range maxRetries {
-				// Initialize stream only if records are empty and it's the first attempt
-				if len(*records) == 0 && attempt == 0 {
-					data, err := r.Reader.ReadStream(ctx)
-					if err != nil {
-						logger.Error("Failed to fetch stream data", slog.Any("error", err))
-						time.Sleep(10 * time.Second)
-						continue
-					}
-					if len(data) == 0 {
-						logger.Info("No data in stream. Retrying...")
-						time.Sleep(10 * time.Second)
-						continue
-					}
-				}
+		// The stream is drained; pick up anything a crashed consumer left.
+		// Entries still waiting in the write buffer come back too and are
+		// left pending.
+		claimed, err := r.Reader.Claim(ctx, claimIdle)
+		if err != nil {
+			logger.Error("Failed to reclaim pending entries", slog.Any("error", err))
+		} else if len(claimed) > 0 && r.applyEntries(ctx, logger, day, claimed, now, loc) > 0 {
+			continue
+		}
 
-				newRecords, recordFetchError = r.Reader.ReadLatest(ctx)
-				if recordFetchError != nil {
-					logger.Error("Failed to fetch data", slog.Any("error", recordFetchError))
-					time.Sleep(10 * time.Second)
+		switch {
+		case !state.TradingDay:
+			report("Market is closed today. Skipping data fetch.",
+				slog.String("reason", r.Schedule.Calendar().Describe(now)))
+		case state.BeforeOpen():
+			report("Market not started yet. Waiting for market to open.")
+		case state.AfterClose():
+			report("Market closed. Stopping data fetch.")
+			if !isReconciled {
+				if queued := r.buffer.Len(); queued > 0 {
+					logger.Info("Waiting for buffered snapshots before reconciling", slog.Int("queued", queued))
 					continue
 				}
 
-				// Validate timestamp
-				if newRecords.TimeStamp != "" {
-					datePart := strings.Split(newRecords.TimeStamp, " ")[0]
-					if now.Format("02-Jan-2006") == datePart {
-						break // Successfully got valid data
-					}
+				if err := r.reconcileDay(ctx, logger, day, now, loc); err != nil {
+					logger.Error("Failed to reconcile day with database", slog.Any("error", err))
+					continue
 				}
 
-				newRecords = models.Records{}
-				if attempt == maxRetries-1 {
-					logger.Warn("Max retries reached, no valid data found")
-				}
+				r.uploadDailyCSV(ctx, logger, day.Records(), day.Aggregates(), now)
+
+				isReconciled = true
 			}
+		}
+	}
+}
+
+// rebuildDay restores today's rows into an empty day after a restart: the
+// rows already in the database first, then today's stream entries that
+// never made it there, which are queued for writing again. Entries whose
+// rows were all persisted are only marked applied; a snapshot only partly
+// written is dropped from what is restored and applied again whole.
+func (r *ProcessingService) rebuildDay(ctx context.Context, logger *slog.Logger, day *Day, now time.Time, loc *time.Location) {
+	dayStart, dayEnd := dayBounds(now, loc)
 
-			if newRecords.TimeStamp != "" {
-				responsePayload := extractResponsePayload(newRecords, loc)
-				if len(responsePayload) > 0 {
-					mu.Lock()
-					*records = append(*records, responsePayload...)
-					mu.Unlock()
-					logger.Info("Added new records", slog.Int("count", len(responsePayload)))
+	entries, err := r.Reader.ReadStream(ctx)
+	if err != nil {
+		logger.Error("Failed to read stream for rebuild", slog.Any("error", err))
+	}
+
+	persisted := map[int64]int{}
+	if r.DBReader != nil {
+		rows, err := r.DBReader.ReadDay(ctx, r.Symbol.Name, dayStart, dayEnd)
+		if err != nil {
+			logger.Error("Failed to read persisted rows for rebuild", slog.Any("error", err))
+		} else if len(rows) > 0 {
+			summaries, err := r.DBReader.ReadSummaries(ctx, r.Symbol.Name, dayStart, dayEnd)
+			if err != nil {
+				logger.Error("Failed to read persisted summaries for rebuild", slog.Any("error", err))
+			}
+			aggregates, err := r.DBReader.ReadAggregates(ctx, r.Symbol.Name, dayStart, dayEnd)
+			if err != nil {
+				logger.Error("Failed to read persisted aggregates for rebuild", slog.Any("error", err))
+			}
+
+			// Each entry yields one row per strike record, so fewer rows
+			// than that means the write was cut short.
+			persisted = countByTimestamp(rows, func(p models.ResponsePayload) time.Time { return p.Timestamp })
+			partial := map[int64]struct{}{}
+			for _, entry := range entries {
+				ts := entryTime(entry.Records, loc).Unix()
+				if n := persisted[ts]; n > 0 && n < len(entry.Records.Data) {
+					partial[ts] = struct{}{}
+					delete(persisted, ts)
 				}
 			}
+			if len(partial) > 0 {
+				logger.Warn("Discarding partly persisted snapshots to apply them again", slog.Int("snapshots", len(partial)))
+				rows = withoutTimestamps(rows, partial, func(p models.ResponsePayload) time.Time { return p.Timestamp })
+				summaries = withoutTimestamps(summaries, partial, func(s models.ChainSummary) time.Time { return s.Timestamp })
+				aggregates = withoutTimestamps(aggregates, partial, func(a models.ChainAggregate) time.Time { return a.Timestamp })
+			}
+
+			// Moneyness is derived, not stored, so persisted rows need it again.
+			tagMoneyness(rows)
+			day.Append(Snapshot{Rows: rows, Summaries: summaries, Aggregates: aggregates})
+			logger.Info("Restored persisted rows", slog.Int("count", len(rows)))
+		}
+	}
+
+	var unpersisted []StreamEntry
+	for _, entry := range entries {
+		if persisted[entryTime(entry.Records, loc).Unix()] > 0 {
+			day.MarkApplied(entry.ID)
+			continue
+		}
+		unpersisted = append(unpersisted, entry)
+	}
+
+	if len(unpersisted) > 0 {
+		r.applyEntries(ctx, logger, day, unpersisted, now, loc)
+		logger.Info("Rebuilt day from stream",
+			slog.Int("entries", len(unpersisted)),
+			slog.Int("count", day.Len()))
+	}
+}
+
+// reconcileDay checks the database holds every snapshot in day in full and
+// writes the ones it is missing or holds only part of, as a last check
+// before the day is uploaded.
+func (r *ProcessingService) reconcileDay(ctx context.Context, logger *slog.Logger, day *Day, now time.Time, loc *time.Location) error {
+	if r.DBReader == nil {
+		return nil
+	}
+	dayStart, dayEnd := dayBounds(now, loc)
+
+	persistedRows, err := r.DBReader.ReadDay(ctx, r.Symbol.Name, dayStart, dayEnd)
+	if err != nil {
+		return err
+	}
+	persistedSummaries, err := r.DBReader.ReadSummaries(ctx, r.Symbol.Name, dayStart, dayEnd)
+	if err != nil {
+		return err
+	}
+	persistedAggregates, err := r.DBReader.ReadAggregates(ctx, r.Symbol.Name, dayStart, dayEnd)
+	if err != nil {
+		return err
+	}
+
+	missingRows := missingByTimestamp(day.Records(), persistedRows,
+		func(p models.ResponsePayload) time.Time { return p.Timestamp })
+	missingSummaries := missingByTimestamp(day.Summaries(), persistedSummaries,
+		func(s models.ChainSummary) time.Time { return s.Timestamp })
+	missingAggregates := missingByTimestamp(day.Aggregates(), persistedAggregates,
+		func(a models.ChainAggregate) time.Time { return a.Timestamp })
+
+	if len(missingRows) > 0 {
+		logger.Warn("Database is missing rows, writing them", slog.Int("count", len(missingRows)))
+		if err := r.DBWriter.WriteToDB(ctx, &missingRows); err != nil {
+			return err
+		}
+	}
+	if len(missingSummaries) > 0 {
+		logger.Warn("Database is missing chain summaries, writing them", slog.Int("count", len(missingSummaries)))
+		if err := r.DBWriter.WriteSummaries(ctx, missingSummaries); err != nil {
+			return err
+		}
+	}
+	if len(missingAggregates) > 0 {
+		logger.Warn("Database is missing chain aggregates, writing them", slog.Int("count", len(missingAggregates)))
+		if err := r.DBWriter.WriteAggregates(ctx, missingAggregates); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Reconciled day with database",
+		slog.Int("persisted_rows", len(persistedRows)),
+		slog.Int("written_rows", len(missingRows)),
+		slog.Int("written_summaries", len(missingSummaries)),
+		slog.Int("written_aggregates", len(missingAggregates)))
+	return nil
+}
+
+// applyEntries adds each of today's entries to day and queues it for the
+// database, which acknowledges it once written. Entries from an earlier
+// date or that can't be decoded are acknowledged without being added, as
+// are entries day has already applied unless their write is still queued.
+// It returns how many entries were added.
+func (r *ProcessingService) applyEntries(ctx context.Context, logger *slog.Logger, day *Day, entries []StreamEntry, now time.Time, loc *time.Location) int {
+	currentDate := now.Format("02-Jan-2006")
+
+	// ids are the entries acknowledged here rather than by the buffer.
+	var ids []string
+	added := 0
+	for _, entry := range entries {
+		if entry.Err != nil {
+			logger.Error("Skipping malformed stream entry",
+				slog.String("id", entry.ID),
+				slog.Any("error", entry.Err))
+			ids = append(ids, entry.ID)
+			continue
+		}
+		if entry.Records.TimeStamp == "" {
+			ids = append(ids, entry.ID)
+			continue
+		}
+		if datePart := strings.Split(entry.Records.TimeStamp, " ")[0]; datePart != currentDate {
+			logger.Warn("Skipping stale stream entry",
+				slog.String("id", entry.ID),
+				slog.String("timestamp", entry.Records.TimeStamp))
+			ids = append(ids, entry.ID)
+			continue
+		}
+		if day.Applied(entry.ID) {
+			logger.Debug("Skipping already applied entry", slog.String("id", entry.ID))
+			if r.buffer == nil || !r.buffer.Holds(entry.ID) {
+				ids = append(ids, entry.ID)
+			}
+			continue
+		}
+
+		responsePayload := extractResponsePayload(r.Symbol.Name, entry.Records, loc, r.Rates)
+		if len(responsePayload) == 0 {
+			ids = append(ids, entry.ID)
+			continue
+		}
+		previous := day.Latest()
+		tagIntervalBuildup(responsePayload, previous)
+		tagIntervalDeltas(responsePayload, previous, rollingSnapshots(day, responsePayload[0].Timestamp))
+		snap := Snapshot{
+			Rows:       responsePayload,
+			Summaries:  extractSummaries(r.Symbol.Name, entry.Records, loc),
+			Aggregates: aggregateChain(responsePayload, r.atmWindow()),
+		}
+		day.Apply(entry.ID, snap)
+		added++
+		if r.buffer != nil {
+			r.buffer.Add(entry.ID, snap)
+		} else {
+			ids = append(ids, entry.ID)
 		}
+		logger.Info("Added new records",
+			slog.String("id", entry.ID),
+			slog.Int("count", len(responsePayload)))
+	}
+
+	if err := r.Reader.Ack(ctx, ids...); err != nil {
+		logger.Error("Failed to acknowledge entries", slog.Any("error", err))
+	}
+	return added
+}
+
+func (r *ProcessingService) atmWindow() int {
+	if r.ATMWindow > 0 {
+		return r.ATMWindow
 	}
+	return DefaultATMWindow
 }
 
 // uploadDailyCSV renders the day's records as CSV and uploads them to the configured bucket, if any.
-func (r *ProcessingService) uploadDailyCSV(ctx context.Context, mu *sync.RWMutex, logger *slog.Logger, records *[]models.ResponsePayload, now time.Time) {
+func (r *ProcessingService) uploadDailyCSV(ctx context.Context, logger *slog.Logger, records []models.ResponsePayload, aggregates []models.ChainAggregate, now time.Time) {
 	if r.Uploader == nil {
 		return
 	}
 
-	mu.RLock()
-	csvData, err := csvexport.ToCSV(*records)
-	mu.RUnlock()
+	csvData, err := csvexport.ToCSV(records, aggregates, r.CSVPrecision)
 	if err != nil {
 		logger.Error("Failed to generate daily CSV", slog.Any("error", err))
 		return
 	}
 
-	key := fmt.Sprintf("nifty50/%s.csv", now.Format("2006-01-02"))
+	key := fmt.Sprintf("%s/%s.csv", r.Symbol.Slug(), now.Format("2006-01-02"))
 	if err := r.Uploader.Upload(ctx, key, csvData); err != nil {
 		logger.Error("Failed to upload daily CSV", slog.Any("error", err))
 		return
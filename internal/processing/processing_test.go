@@ -0,0 +1,289 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"server/internal/greeks"
+	"server/internal/models"
+	"server/internal/symbols"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeReader is a Reader over a fixed stream that records acknowledged IDs.
+type fakeReader struct {
+	stream []StreamEntry
+	acked  []string
+}
+
+func (f *fakeReader) ReadStream(context.Context) ([]StreamEntry, error) { return f.stream, nil }
+func (f *fakeReader) ReadGroup(context.Context, time.Duration) ([]StreamEntry, error) {
+	return nil, nil
+}
+func (f *fakeReader) Claim(context.Context, time.Duration) ([]StreamEntry, error) { return nil, nil }
+func (f *fakeReader) Ack(_ context.Context, ids ...string) error {
+	f.acked = append(f.acked, ids...)
+	return nil
+}
+
+func testEntry(id, timestamp string) StreamEntry {
+	return StreamEntry{
+		ID: id,
+		Records: models.Records{
+			TimeStamp:       timestamp,
+			UnderlyingValue: 24281.4,
+			Data: []models.OptionData{
+				{StrikePrice: 24250, ExpiryDate: "23-Jul-2026", CE: &models.Option{OpenInterest: 100}, PE: &models.Option{OpenInterest: 120}},
+				{StrikePrice: 24300, ExpiryDate: "23-Jul-2026", CE: &models.Option{OpenInterest: 90}, PE: &models.Option{OpenInterest: 80}},
+			},
+		},
+	}
+}
+
+func TestApplyEntriesExactlyOnce(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 17, 11, 45, 0, 0, loc)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	reader := &fakeReader{}
+	svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader}
+	day := NewDay()
+
+	first := testEntry("1-0", "17-Jul-2026 11:43:19")
+	second := testEntry("2-0", "17-Jul-2026 11:46:19")
+	stale := testEntry("0-1", "16-Jul-2026 15:29:59")
+
+	svc.applyEntries(context.Background(), logger, day, []StreamEntry{stale, first}, now, loc)
+	// A redelivery after a failed ack repeats the first entry.
+	svc.applyEntries(context.Background(), logger, day, []StreamEntry{first, second}, now, loc)
+
+	if got := day.Len(); got != 4 {
+		t.Fatalf("day holds %d rows, want 4 (two entries of two strikes)", got)
+	}
+	wantAcked := []string{"0-1", "1-0", "1-0", "2-0"}
+	if !slices.Equal(reader.acked, wantAcked) {
+		t.Errorf("acked %v, want %v", reader.acked, wantAcked)
+	}
+
+	day.Reset()
+	svc.applyEntries(context.Background(), logger, day, []StreamEntry{first}, now, loc)
+	if got := day.Len(); got != 2 {
+		t.Errorf("after reset day holds %d rows, want 2", got)
+	}
+}
+
+// fakeDBReader returns fixed persisted rows.
+type fakeDBReader struct {
+	rows []models.ResponsePayload
+}
+
+func (f fakeDBReader) ReadDay(context.Context, string, time.Time, time.Time) ([]models.ResponsePayload, error) {
+	return f.rows, nil
+}
+
+func (f fakeDBReader) ReadSummaries(context.Context, string, time.Time, time.Time) ([]models.ChainSummary, error) {
+	return nil, nil
+}
+
+func (f fakeDBReader) ReadAggregates(context.Context, string, time.Time, time.Time) ([]models.ChainAggregate, error) {
+	return nil, nil
+}
+
+func TestRebuildDay(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 17, 12, 0, 0, 0, loc)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	first := testEntry("1-0", "17-Jul-2026 11:43:19")
+	second := testEntry("2-0", "17-Jul-2026 11:46:19")
+
+	t.Run("from stream", func(t *testing.T) {
+		reader := &fakeReader{stream: []StreamEntry{first, second}}
+		svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader, DBReader: fakeDBReader{}}
+		svc.buffer = newWriteBuffer(&fakeWriter{}, reader.Ack)
+		day := NewDay()
+
+		svc.rebuildDay(context.Background(), logger, day, now, loc)
+		if got := day.Len(); got != 4 {
+			t.Fatalf("day holds %d rows, want 4", got)
+		}
+		if got := svc.buffer.Len(); got != 2 {
+			t.Errorf("%d snapshots queued for writing, want 2", got)
+		}
+
+		// The group redelivering an entry after the restart adds nothing.
+		svc.applyEntries(context.Background(), logger, day, []StreamEntry{second}, now, loc)
+		if got := day.Len(); got != 4 {
+			t.Errorf("after redelivery day holds %d rows, want 4", got)
+		}
+	})
+
+	t.Run("from database and stream", func(t *testing.T) {
+		persistedRows := extractResponsePayload("NIFTY", first.Records, loc, greeks.Rates{})
+		reader := &fakeReader{stream: []StreamEntry{first, second}}
+		svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader, DBReader: fakeDBReader{rows: persistedRows}}
+		svc.buffer = newWriteBuffer(&fakeWriter{}, reader.Ack)
+		day := NewDay()
+
+		svc.rebuildDay(context.Background(), logger, day, now, loc)
+		if got := day.Len(); got != 4 {
+			t.Fatalf("day holds %d rows, want 4 (2 persisted, 2 from the stream)", got)
+		}
+		if got := svc.buffer.Len(); got != 1 {
+			t.Errorf("%d snapshots queued for writing, want only the unpersisted one", got)
+		}
+
+		svc.applyEntries(context.Background(), logger, day, []StreamEntry{first, second}, now, loc)
+		if got := day.Len(); got != 4 {
+			t.Errorf("stream entries were re-added on top of the rebuilt day: %d rows", got)
+		}
+	})
+
+	t.Run("partly persisted snapshot", func(t *testing.T) {
+		// The second snapshot's write was cut short after one of its rows.
+		persistedRows := append(extractResponsePayload("NIFTY", first.Records, loc, greeks.Rates{}),
+			extractResponsePayload("NIFTY", second.Records, loc, greeks.Rates{})[0])
+		reader := &fakeReader{stream: []StreamEntry{first, second}}
+		svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader, DBReader: fakeDBReader{rows: persistedRows}}
+		svc.buffer = newWriteBuffer(&fakeWriter{}, reader.Ack)
+		day := NewDay()
+
+		svc.rebuildDay(context.Background(), logger, day, now, loc)
+		if got := day.Len(); got != 4 {
+			t.Fatalf("day holds %d rows, want 4 with the partial snapshot applied once", got)
+		}
+		if got := svc.buffer.Len(); got != 1 {
+			t.Errorf("%d snapshots queued for writing, want the partly persisted one", got)
+		}
+	})
+}
+
+func TestReconcileDayRewritesPartialSnapshot(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 17, 15, 40, 0, 0, loc)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	first := testEntry("1-0", "17-Jul-2026 11:43:19")
+	second := testEntry("2-0", "17-Jul-2026 11:46:19")
+	day := NewDay()
+	svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: &fakeReader{}}
+	svc.applyEntries(context.Background(), logger, day, []StreamEntry{first, second}, now, loc)
+
+	secondRows := extractResponsePayload("NIFTY", second.Records, loc, greeks.Rates{})
+	persisted := append(extractResponsePayload("NIFTY", first.Records, loc, greeks.Rates{}), secondRows[0])
+	writer := &fakeWriter{}
+	svc.DBReader, svc.DBWriter = fakeDBReader{rows: persisted}, writer
+
+	if err := svc.reconcileDay(context.Background(), logger, day, now, loc); err != nil {
+		t.Fatalf("reconcileDay: %v", err)
+	}
+	if len(writer.rows) != len(secondRows) || !writer.rows[0].Timestamp.Equal(secondRows[0].Timestamp) {
+		t.Errorf("rewrote %d rows, want the %d rows of the partly written snapshot", len(writer.rows), len(secondRows))
+	}
+}
+
+// fakeWriter is a DBWriter whose first failures calls to WriteToDB fail.
+// It records what it wrote.
+type fakeWriter struct {
+	failures  int
+	rows      []models.ResponsePayload
+	summaries []models.ChainSummary
+}
+
+func (f *fakeWriter) WriteToDB(_ context.Context, records *[]models.ResponsePayload) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("database unavailable")
+	}
+	f.rows = append(f.rows, *records...)
+	return nil
+}
+
+func (f *fakeWriter) WriteSummaries(_ context.Context, summaries []models.ChainSummary) error {
+	f.summaries = append(f.summaries, summaries...)
+	return nil
+}
+
+func (f *fakeWriter) WriteAggregates(context.Context, []models.ChainAggregate) error { return nil }
+
+func TestWriteBufferRetriesInOrder(t *testing.T) {
+	writer := &fakeWriter{failures: 1}
+	reader := &fakeReader{}
+	buffer := newWriteBuffer(writer, reader.Ack)
+
+	buffer.Add("1-0", Snapshot{Rows: []models.ResponsePayload{{StrikePrice: 1}}})
+	buffer.Add("2-0", Snapshot{Rows: []models.ResponsePayload{{StrikePrice: 2}}})
+
+	if err := buffer.flush(context.Background()); err == nil {
+		t.Fatal("flush succeeded against a failing database")
+	}
+	if got := buffer.Len(); got != 2 {
+		t.Fatalf("%d snapshots queued after a failed flush, want 2", got)
+	}
+	if len(reader.acked) != 0 {
+		t.Fatalf("acked %v before anything was written", reader.acked)
+	}
+
+	if err := buffer.flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if got := buffer.Len(); got != 0 {
+		t.Errorf("%d snapshots still queued, want 0", got)
+	}
+	if len(writer.rows) != 2 || writer.rows[0].StrikePrice != 1 || writer.rows[1].StrikePrice != 2 {
+		t.Errorf("wrote %+v, want strikes 1 then 2", writer.rows)
+	}
+	if want := []string{"1-0", "2-0"}; !slices.Equal(reader.acked, want) {
+		t.Errorf("acked %v, want %v", reader.acked, want)
+	}
+}
+
+func TestApplyEntriesAcksOnceWritten(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 17, 11, 50, 0, 0, loc)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	reader := &fakeReader{}
+	svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader}
+	svc.buffer = newWriteBuffer(&fakeWriter{failures: 1}, reader.Ack)
+	day := NewDay()
+
+	first := testEntry("1-0", "17-Jul-2026 11:43:19")
+	stale := testEntry("0-1", "16-Jul-2026 15:29:59")
+
+	if added := svc.applyEntries(context.Background(), logger, day, []StreamEntry{stale, first}, now, loc); added != 1 {
+		t.Fatalf("added %d entries, want 1", added)
+	}
+	if err := svc.buffer.flush(context.Background()); err == nil {
+		t.Fatal("flush succeeded against a failing database")
+	}
+	// Reclaiming the entry while its write is queued leaves it pending.
+	if added := svc.applyEntries(context.Background(), logger, day, []StreamEntry{first}, now, loc); added != 0 {
+		t.Fatalf("re-added %d entries still queued for writing", added)
+	}
+	if want := []string{"0-1"}; !slices.Equal(reader.acked, want) {
+		t.Fatalf("acked %v before the write, want only the stale entry", reader.acked)
+	}
+
+	if err := svc.buffer.flush(context.Background()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if want := []string{"0-1", "1-0"}; !slices.Equal(reader.acked, want) {
+		t.Errorf("acked %v, want %v", reader.acked, want)
+	}
+}
@@ -0,0 +1,147 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"server/internal/db"
+	"server/internal/symbols"
+	"time"
+)
+
+const (
+	retentionInterval = 1 * time.Hour
+
+	DefaultPartitionAheadDays = 7
+)
+
+// RetentionService keeps option_chain_snapshots' daily partitions in
+// shape: it creates partitions ahead of time and removes ones older than
+// the retention period, but only once every symbol in them has its daily
+// CSV in the bucket.
+type RetentionService struct {
+	Store PartitionStore
+	// Archive verifies daily CSVs exist. Without it nothing is removed.
+	Archive ArchiveChecker
+	// AheadDays is how many days of partitions, starting today, to keep
+	// created. Zero means DefaultPartitionAheadDays.
+	AheadDays int
+	// RetentionDays is how many days of partitions, including today, to
+	// keep. Zero keeps everything.
+	RetentionDays int
+	// DetachOnly detaches expired partitions instead of dropping them,
+	// leaving them as plain tables to drop by hand.
+	DetachOnly bool
+	Location   *time.Location
+}
+
+// Run maintains partitions now and then hourly until ctx is cancelled.
+func (s *RetentionService) Run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Maintain(ctx, logger, time.Now().In(s.Location)); err != nil {
+			logger.Error("Partition maintenance failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Maintain creates upcoming partitions and removes expired, archived ones
+// as of now.
+func (s *RetentionService) Maintain(ctx context.Context, logger *slog.Logger, now time.Time) error {
+	aheadDays := s.AheadDays
+	if aheadDays == 0 {
+		aheadDays = DefaultPartitionAheadDays
+	}
+
+	created, err := s.Store.EnsurePartitions(ctx, now, aheadDays)
+	for _, name := range created {
+		logger.Info("Created partition", slog.String("partition", name))
+	}
+	if err != nil {
+		// Days that failed are retried on the next run; retention below
+		// doesn't depend on them.
+		logger.Error("Failed to create partitions", slog.Any("error", err))
+	}
+
+	if stray, err := s.Store.DefaultPartitionRows(ctx); err != nil {
+		logger.Error("Failed to check default partition", slog.Any("error", err))
+	} else if stray > 0 {
+		logger.Warn("Rows landed in the default partition; their days had no partition",
+			slog.Int64("rows", stray))
+	}
+
+	if s.RetentionDays == 0 {
+		return nil
+	}
+
+	today, _ := dayBounds(now, s.Location)
+	cutoff := today.AddDate(0, 0, 1-s.RetentionDays)
+
+	partitions, err := s.Store.Partitions(ctx, s.Location)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range partitions {
+		if !p.Day.Before(cutoff) {
+			break
+		}
+
+		if s.Archive == nil {
+			logger.Warn("Partition past retention kept: no bucket to verify its archive",
+				slog.String("partition", p.Name))
+			continue
+		}
+
+		missing, err := s.unarchived(ctx, p)
+		if err != nil {
+			logger.Error("Failed to verify partition archive",
+				slog.String("partition", p.Name), slog.Any("error", err))
+			continue
+		}
+		if len(missing) > 0 {
+			logger.Warn("Partition past retention kept: daily CSVs missing from bucket",
+				slog.String("partition", p.Name), slog.Any("missing", missing))
+			continue
+		}
+
+		if err := s.Store.RemovePartition(ctx, p.Name, s.DetachOnly); err != nil {
+			logger.Error("Failed to remove partition",
+				slog.String("partition", p.Name), slog.Any("error", err))
+			continue
+		}
+		logger.Info("Removed partition past retention",
+			slog.String("partition", p.Name), slog.Bool("detached_only", s.DetachOnly))
+	}
+	return nil
+}
+
+// unarchived returns the bucket keys of the daily CSVs missing or empty
+// for the symbols that have rows in p.
+func (s *RetentionService) unarchived(ctx context.Context, p db.Partition) ([]string, error) {
+	counts, err := s.Store.PartitionSymbols(ctx, p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for symbol := range counts {
+		key := fmt.Sprintf("%s/%s.csv", symbols.Symbol{Name: symbol}.Slug(), p.Day.Format("2006-01-02"))
+		size, err := s.Archive.ObjectSize(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,124 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"server/internal/db"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakePartitions is a PartitionStore over an in-memory set of partitions.
+type fakePartitions struct {
+	partitions []db.Partition
+	symbols    map[string]map[string]int64
+	created    []string
+	removed    []string
+	// failing partitions can't be created.
+	failing map[string]bool
+}
+
+func (f *fakePartitions) EnsurePartitions(_ context.Context, from time.Time, days int) ([]string, error) {
+	var errs []error
+	for i := range days {
+		name := db.PartitionName(from.AddDate(0, 0, i))
+		if f.failing[name] {
+			errs = append(errs, fmt.Errorf("failed to create partition %s", name))
+			continue
+		}
+		if !slices.Contains(f.created, name) {
+			f.created = append(f.created, name)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *fakePartitions) Partitions(context.Context, *time.Location) ([]db.Partition, error) {
+	return f.partitions, nil
+}
+
+func (f *fakePartitions) PartitionSymbols(_ context.Context, name string) (map[string]int64, error) {
+	return f.symbols[name], nil
+}
+
+func (f *fakePartitions) DefaultPartitionRows(context.Context) (int64, error) { return 0, nil }
+
+func (f *fakePartitions) RemovePartition(_ context.Context, name string, _ bool) error {
+	f.removed = append(f.removed, name)
+	return nil
+}
+
+// fakeArchive is an ArchiveChecker over a fixed set of object sizes.
+type fakeArchive map[string]int64
+
+func (f fakeArchive) ObjectSize(_ context.Context, key string) (int64, error) { return f[key], nil }
+
+func TestRetentionRemovesOnlyArchivedExpiredPartitions(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 7, d, 0, 0, 0, 0, loc) }
+	partition := func(d int) db.Partition { return db.Partition{Name: db.PartitionName(day(d)), Day: day(d)} }
+
+	store := &fakePartitions{
+		partitions: []db.Partition{partition(10), partition(13), partition(14), partition(16), partition(17)},
+		symbols: map[string]map[string]int64{
+			db.PartitionName(day(10)): {"NIFTY": 100, "BANKNIFTY": 80},
+			db.PartitionName(day(13)): {"NIFTY": 100, "BANKNIFTY": 80},
+			db.PartitionName(day(14)): {},
+		},
+	}
+	archive := fakeArchive{
+		"nifty50/2026-07-10.csv":   2048,
+		"banknifty/2026-07-10.csv": 1024,
+		// BANKNIFTY's CSV for the 13th never made it to the bucket.
+		"nifty50/2026-07-13.csv": 2048,
+	}
+	svc := &RetentionService{Store: store, Archive: archive, RetentionDays: 3, Location: loc}
+
+	now := time.Date(2026, 7, 17, 10, 0, 0, 0, loc)
+	if err := svc.Maintain(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), now); err != nil {
+		t.Fatalf("Maintain: %v", err)
+	}
+
+	// Keeping 3 days keeps the 15th onwards. The 13th is past retention
+	// but not fully archived; the 14th holds no rows, so nothing is owed.
+	wantRemoved := []string{db.PartitionName(day(10)), db.PartitionName(day(14))}
+	if !slices.Equal(store.removed, wantRemoved) {
+		t.Errorf("removed %v, want %v", store.removed, wantRemoved)
+	}
+	if len(store.created) != DefaultPartitionAheadDays || store.created[0] != db.PartitionName(day(17)) {
+		t.Errorf("created %v, want %d days from today", store.created, DefaultPartitionAheadDays)
+	}
+}
+
+func TestRetentionContinuesPastFailedPartition(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2026, 7, d, 0, 0, 0, 0, loc) }
+
+	store := &fakePartitions{
+		partitions: []db.Partition{{Name: db.PartitionName(day(10)), Day: day(10)}},
+		failing:    map[string]bool{db.PartitionName(day(17)): true},
+	}
+	svc := &RetentionService{Store: store, Archive: fakeArchive{}, RetentionDays: 3, Location: loc}
+
+	now := time.Date(2026, 7, 17, 10, 0, 0, 0, loc)
+	if err := svc.Maintain(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), now); err != nil {
+		t.Fatalf("Maintain: %v", err)
+	}
+
+	if len(store.created) != DefaultPartitionAheadDays-1 || slices.Contains(store.created, db.PartitionName(day(17))) {
+		t.Errorf("created %v, want every day but the failing 17th", store.created)
+	}
+	if want := []string{db.PartitionName(day(10))}; !slices.Equal(store.removed, want) {
+		t.Errorf("removed %v, want %v despite the failed partition", store.removed, want)
+	}
+}
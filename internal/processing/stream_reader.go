@@ -3,6 +3,9 @@ package processing
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
+	"time"
 
 	redis "github.com/redis/go-redis/v9"
 
@@ -10,56 +13,181 @@ import (
 )
 
 type StreamReader struct {
-	reader *redis.Client
-	stream string
+	reader   *redis.Client
+	stream   string
+	group    string
+	consumer string
+
+	groupReady bool
 }
 
-func NewStreamReader(client *redis.Client, stream string) *StreamReader {
+// NewStreamReader reads stream as consumer within the given consumer group.
+// The group is created on first use, and again whenever the fetcher's daily
+// reset deletes the stream along with it.
+func NewStreamReader(client *redis.Client, stream, group, consumer string) *StreamReader {
 	return &StreamReader{
-		reader: client,
-		stream: stream,
+		reader:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
 	}
 }
 
-func (r *StreamReader) ReadStream(ctx context.Context) ([]models.Records, error) {
-	var records []models.Records
-
+// ReadStream returns every entry currently in the stream, oldest first,
+// regardless of what the consumer group has already seen.
+func (r *StreamReader) ReadStream(ctx context.Context) ([]StreamEntry, error) {
 	stream, err := r.reader.XRange(ctx, r.stream, "-", "+").Result()
 	if err != nil {
-		return records, err
+		return nil, err
 	}
+	return decodeEntries(stream), nil
+}
 
-	for _, msg := range stream {
-		if data, ok := msg.Values["data"].(string); ok {
-			var record models.Records
-			if err := json.Unmarshal([]byte(data), &record); err != nil {
-				return nil, err
-			}
-			records = append(records, record)
-		}
+// ReadGroup blocks for up to block waiting for entries not yet delivered to
+// the group. It returns no entries and no error when the wait times out.
+func (r *StreamReader) ReadGroup(ctx context.Context, block time.Duration) ([]StreamEntry, error) {
+	if err := r.ensureGroup(ctx); err != nil {
+		return nil, err
 	}
 
-	return records, nil
+	streams, err := r.reader.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    r.group,
+		Consumer: r.consumer,
+		Streams:  []string{r.stream, ">"},
+		Count:    readGroupCount,
+		Block:    block,
+	}).Result()
+	if isNoGroup(err) {
+		// The stream was deleted and recreated since ensureGroup ran.
+		if err := r.createGroup(ctx); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	var entries []StreamEntry
+	for _, s := range streams {
+		entries = append(entries, decodeEntries(s.Messages)...)
+	}
+	return entries, nil
 }
 
-func (r *StreamReader) ReadLatest(ctx context.Context) (models.Records, error) {
-	var record models.Records
+// Claim returns entries that were delivered but never acknowledged: first
+// this consumer's own backlog from before a restart, then entries another
+// consumer has left idle for longer than minIdle, which are claimed for
+// this consumer.
+func (r *StreamReader) Claim(ctx context.Context, minIdle time.Duration) ([]StreamEntry, error) {
+	if err := r.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
 
-	stream, err := r.reader.XRevRangeN(ctx, r.stream, "+", "-", 1).Result()
-	if err != nil {
-		return record, err
+	streams, err := r.reader.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    r.group,
+		Consumer: r.consumer,
+		Streams:  []string{r.stream, "0"},
+		Count:    readGroupCount,
+		Block:    -1,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		if isNoGroup(err) {
+			return nil, r.createGroup(ctx)
+		}
+		return nil, err
 	}
 
-	if len(stream) == 0 {
-		return record, nil
-	}  
+	var entries []StreamEntry
+	for _, s := range streams {
+		entries = append(entries, decodeEntries(s.Messages)...)
+	}
+
+	start := "0-0"
+	for {
+		msgs, next, err := r.reader.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   r.stream,
+			Group:    r.group,
+			Consumer: r.consumer,
+			MinIdle:  minIdle,
+			Start:    start,
+			Count:    readGroupCount,
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, decodeEntries(msgs)...)
 
-	if data, ok := stream[0].Values["data"].(string); ok {
-		if err := json.Unmarshal([]byte(data), &record); err != nil {
-			return record, err
+		if next == "0-0" || next == "" {
+			break
 		}
+		start = next
 	}
-	return record, nil
+	return entries, nil
+}
+
+// Ack marks entries as processed so they are not redelivered.
+func (r *StreamReader) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.reader.XAck(ctx, r.stream, r.group, ids...).Err()
+	if isNoGroup(err) {
+		// The stream was reset; there is nothing left to acknowledge.
+		return nil
+	}
+	return err
+}
+
+const readGroupCount = 100
+
+// ensureGroup creates the consumer group on first use. Later resets are
+// caught by the NOGROUP error instead of checking on every read.
+func (r *StreamReader) ensureGroup(ctx context.Context) error {
+	if r.groupReady {
+		return nil
+	}
+	if err := r.createGroup(ctx); err != nil {
+		return err
+	}
+	r.groupReady = true
+	return nil
+}
 
+// createGroup creates the consumer group from the start of the stream,
+// creating the stream too if the fetcher hasn't written to it yet.
+func (r *StreamReader) createGroup(ctx context.Context) error {
+	err := r.reader.XGroupCreateMkStream(ctx, r.stream, r.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func isNoGroup(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOGROUP")
+}
+
+// decodeEntries decodes stream messages. Entries deleted from the stream
+// while pending come back without values and are returned with zero
+// Records so the caller can still acknowledge them. An entry that fails to
+// decode is returned with Err set rather than failing its batch, since it
+// would otherwise stay pending and fail every later claim too.
+func decodeEntries(msgs []redis.XMessage) []StreamEntry {
+	entries := make([]StreamEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		entry := StreamEntry{ID: msg.ID}
+		if data, ok := msg.Values["data"].(string); ok {
+			if err := json.Unmarshal([]byte(data), &entry.Records); err != nil {
+				entry.Records = models.Records{}
+				entry.Err = err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
 }
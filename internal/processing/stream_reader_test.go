@@ -0,0 +1,57 @@
+package processing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"server/internal/symbols"
+	"slices"
+	"testing"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+func TestMalformedEntryDoesNotBlockBatch(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 7, 17, 11, 50, 0, 0, loc)
+
+	message := func(id, timestamp string) redis.XMessage {
+		data, err := json.Marshal(testEntry(id, timestamp).Records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return redis.XMessage{ID: id, Values: map[string]any{"data": string(data)}}
+	}
+	batch := []redis.XMessage{
+		message("1-0", "17-Jul-2026 11:43:19"),
+		{ID: "2-0", Values: map[string]any{"data": `{"timestamp": "17-Jul-2026 11:46:19", "data": [`}},
+		message("3-0", "17-Jul-2026 11:49:19"),
+	}
+
+	entries := decodeEntries(batch)
+	if len(entries) != 3 {
+		t.Fatalf("decoded %d entries, want all 3", len(entries))
+	}
+	if entries[0].Err != nil || entries[1].Err == nil || entries[2].Err != nil {
+		t.Fatalf("decode errors = %v, %v, %v, want only the second entry's",
+			entries[0].Err, entries[1].Err, entries[2].Err)
+	}
+
+	reader := &fakeReader{}
+	svc := &ProcessingService{Symbol: symbols.Symbol{Name: "NIFTY"}, Reader: reader}
+	day := NewDay()
+	svc.applyEntries(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), day, entries, now, loc)
+
+	if got := day.Len(); got != 4 {
+		t.Errorf("day holds %d rows, want the 4 from the two good entries", got)
+	}
+	// The malformed entry is acknowledged so it doesn't stay pending.
+	if want := []string{"1-0", "2-0", "3-0"}; !slices.Equal(reader.acked, want) {
+		t.Errorf("acked %v, want %v", reader.acked, want)
+	}
+}
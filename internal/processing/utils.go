@@ -2,23 +2,22 @@ package processing
 
 import (
 	"math"
+	"server/internal/greeks"
 	"server/internal/models"
 	"time"
 )
 
-func isMarketHoliday(date time.Time, loc *time.Location) bool {
-	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
-		return true
-	}
-
-	return false
-}
+// expiryClose is when an expiring contract stops trading, as an offset
+// from midnight of its expiry date.
+const expiryClose = 15*time.Hour + 30*time.Minute
 
-func extractResponsePayload(records models.Records, loc *time.Location) []models.ResponsePayload {
+func extractResponsePayload(symbol string, records models.Records, loc *time.Location, rates greeks.Rates) []models.ResponsePayload {
 	var response []models.ResponsePayload
 	for _, record := range records.Data {
-		ceOI, ceChOI, ceVol, ceIV, ceLTP := 0.0, 0.0, 0, 0.0, 0.0
-		peOI, peChOI, peVol, peIV, peLTP := 0.0, 0.0, 0, 0.0, 0.0
+		ceOI, ceChOI, ceVol, ceIV, ceLTP, ceChange := 0.0, 0.0, 0, 0.0, 0.0, 0.0
+		peOI, peChOI, peVol, peIV, peLTP, peChange := 0.0, 0.0, 0, 0.0, 0.0, 0.0
+		ceBid, ceBidQty, ceAsk, ceAskQty := 0.0, 0, 0.0, 0
+		peBid, peBidQty, peAsk, peAskQty := 0.0, 0, 0.0, 0
 
 		if record.CE != nil {
 			ceOI = record.CE.OpenInterest
@@ -26,6 +25,9 @@ func extractResponsePayload(records models.Records, loc *time.Location) []models
 			ceVol = record.CE.TotalTradedVolume
 			ceIV = record.CE.ImpliedVolatility
 			ceLTP = record.CE.LastPrice
+			ceChange = record.CE.Change
+			ceBid, ceBidQty = record.CE.BidPrice, record.CE.BidQty
+			ceAsk, ceAskQty = record.CE.AskPrice, record.CE.AskQty
 		}
 		if record.PE != nil {
 			peOI = record.PE.OpenInterest
@@ -33,6 +35,9 @@ func extractResponsePayload(records models.Records, loc *time.Location) []models
 			peVol = record.PE.TotalTradedVolume
 			peIV = record.PE.ImpliedVolatility
 			peLTP = record.PE.LastPrice
+			peChange = record.PE.Change
+			peBid, peBidQty = record.PE.BidPrice, record.PE.BidQty
+			peAsk, peAskQty = record.PE.AskPrice, record.PE.AskQty
 		}
 
 		pcr := calculatePCR(peOI, ceOI)
@@ -49,7 +54,14 @@ func extractResponsePayload(records models.Records, loc *time.Location) []models
 			expiryDate = time.Time{}
 		}
 
+		years := expiryDate.Add(expiryClose).Sub(timeStamp).Hours() / (24 * 365)
+		ceIV, ceIVComputed := impliedVolatility(greeks.Call, ceIV, ceLTP, ceBid, ceAsk, records.UnderlyingValue, record.StrikePrice, years, rates)
+		peIV, peIVComputed := impliedVolatility(greeks.Put, peIV, peLTP, peBid, peAsk, records.UnderlyingValue, record.StrikePrice, years, rates)
+		ceDelta, ceGamma, ceTheta, ceVega, ceRho := optionGreeks(greeks.Call, records.UnderlyingValue, record.StrikePrice, years, ceIV, rates)
+		peDelta, peGamma, peTheta, peVega, peRho := optionGreeks(greeks.Put, records.UnderlyingValue, record.StrikePrice, years, peIV, rates)
+
 		response = append(response, models.ResponsePayload{
+			Symbol:                           symbol,
 			Timestamp:                        timeStamp,
 			ExpiryDate:                       expiryDate,
 			StrikePrice:                      record.StrikePrice,
@@ -59,25 +71,191 @@ func extractResponsePayload(records models.Records, loc *time.Location) []models
 			CEChangeInOpenInterestPercentage: ceChOIPercentage,
 			CETotalTradedVolume:              ceVol,
 			CEImpliedVolatility:              ceIV,
+			CEIVComputed:                     ceIVComputed,
 			CELastPrice:                      ceLTP,
+			CEBidPrice:                       ceBid,
+			CEBidQty:                         ceBidQty,
+			CEAskPrice:                       ceAsk,
+			CEAskQty:                         ceAskQty,
 			PEOpenInterest:                   peOI,
 			PEChangeInOpenInterest:           peChOI,
 			PEChangeInOpenInterestPercentage: peChOIPercentage,
 			PETotalTradedVolume:              peVol,
 			PEImpliedVolatility:              peIV,
+			PEIVComputed:                     peIVComputed,
 			PELastPrice:                      peLTP,
+			PEBidPrice:                       peBid,
+			PEBidQty:                         peBidQty,
+			PEAskPrice:                       peAsk,
+			PEAskQty:                         peAskQty,
 			PCR:                              pcr,
 			IntraDayPCR:                      intradayPCR,
+			CEPriceChange:                    ceChange,
+			PEPriceChange:                    peChange,
+			CEBuildup:                        classifyBuildup(ceChange, ceChOI),
+			PEBuildup:                        classifyBuildup(peChange, peChOI),
+			CEDelta:                          ceDelta,
+			CEGamma:                          ceGamma,
+			CETheta:                          ceTheta,
+			CEVega:                           ceVega,
+			CERho:                            ceRho,
+			PEDelta:                          peDelta,
+			PEGamma:                          peGamma,
+			PETheta:                          peTheta,
+			PEVega:                           peVega,
+			PERho:                            peRho,
 		})
 	}
+	tagMoneyness(response)
 	return response
 }
 
-func calculatePCR(num, denom float64) float64 {
+// extractSummaries builds one ChainSummary per expiry from the NSE totals
+// the fetcher attached to the snapshot.
+func extractSummaries(symbol string, records models.Records, loc *time.Location) []models.ChainSummary {
+	timeStamp, err := time.ParseInLocation("02-Jan-2006 15:04:05", records.TimeStamp, loc)
+	if err != nil {
+		timeStamp = time.Time{}
+	}
+
+	var summaries []models.ChainSummary
+	for _, t := range records.Totals {
+		expiryDate, err := time.ParseInLocation("02-Jan-2006", t.ExpiryDate, loc)
+		if err != nil {
+			expiryDate = time.Time{}
+		}
+
+		summaries = append(summaries, models.ChainSummary{
+			Symbol:          symbol,
+			Timestamp:       timeStamp,
+			ExpiryDate:      expiryDate,
+			UnderlyingValue: records.UnderlyingValue,
+			CETotalOI:       t.CETotalOI,
+			CETotalVolume:   t.CETotalVolume,
+			PETotalOI:       t.PETotalOI,
+			PETotalVolume:   t.PETotalVolume,
+			PCR:             calculatePCR(t.PETotalOI, t.CETotalOI),
+			VolumePCR:       calculatePCR(t.PETotalVolume, t.CETotalVolume),
+			StrikePrices:    records.StrikePrices,
+		})
+	}
+	return summaries
+}
+
+// impliedVolatility returns NSE's IV, in percent, when it reported one.
+// Otherwise it solves for the IV from the last price, or from the bid/ask
+// mid when the contract hasn't traded, and reports it as computed. It
+// returns 0, false when there is no price to solve from or no volatility
+// reproduces it.
+func impliedVolatility(typ greeks.OptionType, reported, ltp, bid, ask, underlying, strike, years float64, rates greeks.Rates) (float64, bool) {
+	if reported > 0 {
+		return reported, false
+	}
+
+	price := ltp
+	if price <= 0 && bid > 0 && ask > 0 {
+		price = (bid + ask) / 2
+	}
+	if price <= 0 {
+		return 0, false
+	}
+
+	iv, ok := greeks.ImpliedVolatility(typ, price, greeks.Inputs{
+		Underlying:    underlying,
+		Strike:        strike,
+		Years:         years,
+		Rate:          rates.RiskFree,
+		DividendYield: rates.DividendYield,
+	})
+	if !ok {
+		return 0, false
+	}
+	return iv * 100, true
+}
+
+// optionGreeks prices one side of a strike from its IV, which is quoted in
+// percent, returning delta, gamma, theta, vega and rho. All are nil when
+// the side can't be priced: it has no IV, reported or solved, or expired.
+func optionGreeks(typ greeks.OptionType, underlying, strike, years, iv float64, rates greeks.Rates) (delta, gamma, theta, vega, rho *float64) {
+	g, ok := greeks.BlackScholes(typ, greeks.Inputs{
+		Underlying:    underlying,
+		Strike:        strike,
+		Years:         years,
+		Volatility:    iv / 100,
+		Rate:          rates.RiskFree,
+		DividendYield: rates.DividendYield,
+	})
+	if !ok {
+		return nil, nil, nil, nil, nil
+	}
+	return &g.Delta, &g.Gamma, &g.Theta, &g.Vega, &g.Rho
+}
+
+// entryTime parses a snapshot's NSE timestamp, returning the zero time if
+// it is malformed, as the row builders do.
+func entryTime(records models.Records, loc *time.Location) time.Time {
+	timeStamp, err := time.ParseInLocation("02-Jan-2006 15:04:05", records.TimeStamp, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return timeStamp
+}
+
+// dayBounds returns the start of now's day in loc and the start of the
+// next.
+func dayBounds(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// countByTimestamp returns how many of items each snapshot time, as Unix
+// seconds, covers.
+func countByTimestamp[T any](items []T, timestamp func(T) time.Time) map[int64]int {
+	counts := make(map[int64]int)
+	for _, item := range items {
+		counts[timestamp(item).Unix()]++
+	}
+	return counts
+}
+
+// withoutTimestamps returns the items whose snapshot time is not in drop.
+func withoutTimestamps[T any](items []T, drop map[int64]struct{}, timestamp func(T) time.Time) []T {
+	if len(drop) == 0 {
+		return items
+	}
+	var kept []T
+	for _, item := range items {
+		if _, ok := drop[timestamp(item).Unix()]; !ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// missingByTimestamp returns the items of have for every snapshot time at
+// which persisted holds fewer items than have, so a snapshot only partly
+// written before a crash is written again whole. Writes are upserts, so
+// the items that did make it are rewritten harmlessly.
+func missingByTimestamp[T any](have, persisted []T, timestamp func(T) time.Time) []T {
+	want := countByTimestamp(have, timestamp)
+	got := countByTimestamp(persisted, timestamp)
+	var missing []T
+	for _, item := range have {
+		if ts := timestamp(item).Unix(); got[ts] < want[ts] {
+			missing = append(missing, item)
+		}
+	}
+	return missing
+}
+
+// calculatePCR returns num/denom, or nil when the ratio is undefined
+// because denom is zero.
+func calculatePCR(num, denom float64) *float64 {
 	if denom == 0 || !isFinite(num/denom) {
-		return -1
+		return nil
 	}
-	return num / denom
+	ratio := num / denom
+	return &ratio
 }
 
 func calculatePercentage(changeOI, baseOI float64) float64 {
@@ -0,0 +1,33 @@
+package processing
+
+import (
+	"math"
+	"server/internal/greeks"
+	"testing"
+)
+
+func TestImpliedVolatilityFillsMissingIV(t *testing.T) {
+	rates := greeks.Rates{RiskFree: 0.065}
+	years := 10.0 / 365
+	in := greeks.Inputs{Underlying: 24000, Strike: 24200, Years: years, Volatility: 0.14, Rate: rates.RiskFree}
+	quote, _ := greeks.BlackScholes(greeks.Call, in)
+
+	if iv, computed := impliedVolatility(greeks.Call, 13.5, quote.Price, 0, 0, 24000, 24200, years, rates); iv != 13.5 || computed {
+		t.Errorf("reported IV: got %v computed=%v, want 13.5 as reported", iv, computed)
+	}
+
+	iv, computed := impliedVolatility(greeks.Call, 0, quote.Price, 0, 0, 24000, 24200, years, rates)
+	if !computed || math.Abs(iv-14) > 0.01 {
+		t.Errorf("from LTP: got %v computed=%v, want 14 computed", iv, computed)
+	}
+
+	// Untraded contract: solve from the mid of a spread around fair value.
+	iv, computed = impliedVolatility(greeks.Call, 0, 0, quote.Price-1, quote.Price+1, 24000, 24200, years, rates)
+	if !computed || math.Abs(iv-14) > 0.01 {
+		t.Errorf("from mid: got %v computed=%v, want 14 computed", iv, computed)
+	}
+
+	if iv, computed := impliedVolatility(greeks.Call, 0, 0, 0, quote.Price, 24000, 24200, years, rates); iv != 0 || computed {
+		t.Errorf("one-sided quote: got %v computed=%v, want no IV", iv, computed)
+	}
+}
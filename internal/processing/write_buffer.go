@@ -0,0 +1,137 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	minWriteRetry = 2 * time.Second
+	maxWriteRetry = 1 * time.Minute
+)
+
+// pendingSnapshot is a queued snapshot, the stream entry it came from and
+// how many of its tables have been written, so a retry resumes where the
+// last attempt failed.
+type pendingSnapshot struct {
+	Snapshot
+	id      string
+	written int
+}
+
+// writeBuffer queues snapshots for the database and writes them in arrival
+// order from a single goroutine. While the database is unreachable it keeps
+// them queued and retries with backoff. The queue lives only in memory, so
+// a snapshot's stream entry is acknowledged only once all its tables are
+// written: until then the entry stays pending in the consumer group, and a
+// restarted processor rebuilds the snapshot from it.
+type writeBuffer struct {
+	writer DBWriter
+	ack    func(ctx context.Context, ids ...string) error
+
+	mu     sync.Mutex
+	queue  []*pendingSnapshot
+	notify chan struct{}
+}
+
+func newWriteBuffer(writer DBWriter, ack func(ctx context.Context, ids ...string) error) *writeBuffer {
+	return &writeBuffer{writer: writer, ack: ack, notify: make(chan struct{}, 1)}
+}
+
+// Add queues the snapshot decoded from stream entry id for writing.
+func (b *writeBuffer) Add(id string, snap Snapshot) {
+	b.mu.Lock()
+	b.queue = append(b.queue, &pendingSnapshot{Snapshot: snap, id: id})
+	b.mu.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of snapshots not yet written.
+func (b *writeBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.queue)
+}
+
+// Holds reports whether the snapshot of stream entry id is still queued.
+func (b *writeBuffer) Holds(id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.queue {
+		if p.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Run writes queued snapshots until ctx is cancelled.
+func (b *writeBuffer) Run(ctx context.Context, logger *slog.Logger) {
+	retry := minWriteRetry
+	for {
+		if err := b.flush(ctx); err != nil {
+			logger.Error("Failed to persist snapshot, will retry",
+				slog.Any("error", err),
+				slog.Int("queued", b.Len()),
+				slog.Duration("retry_in", retry))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, maxWriteRetry)
+			continue
+		}
+		retry = minWriteRetry
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.notify:
+		}
+	}
+}
+
+// flush writes queued snapshots oldest first and acknowledges each one's
+// stream entry, stopping at the first failure so ordering is kept. A
+// snapshot that failed part way only retries the steps not yet done.
+func (b *writeBuffer) flush(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if len(b.queue) == 0 {
+			b.mu.Unlock()
+			return nil
+		}
+		head := b.queue[0]
+		b.mu.Unlock()
+
+		writes := []func() error{
+			func() error { return b.writer.WriteToDB(ctx, &head.Rows) },
+			func() error { return b.writer.WriteSummaries(ctx, head.Summaries) },
+			func() error { return b.writer.WriteAggregates(ctx, head.Aggregates) },
+			func() error {
+				if err := b.ack(ctx, head.id); err != nil {
+					return fmt.Errorf("failed to acknowledge entry %s: %w", head.id, err)
+				}
+				return nil
+			},
+		}
+		for ; head.written < len(writes); head.written++ {
+			if err := writes[head.written](); err != nil {
+				return err
+			}
+		}
+
+		b.mu.Lock()
+		b.queue = b.queue[1:]
+		b.mu.Unlock()
+	}
+}
@@ -3,11 +3,14 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type BucketUploader struct {
@@ -27,15 +30,46 @@ func NewBucketUploader(endpoint, region, bucket, accessKeyID, secretAccessKey st
 }
 
 // Upload writes data to <bucket>/<key>, overwriting if it already exists.
+// The content type is inferred from the key's extension.
 func (b *BucketUploader) Upload(ctx context.Context, key string, data []byte) error {
 	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(b.bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(data),
-		ContentType: aws.String("text/csv"),
+		ContentType: aws.String(contentType(key)),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to upload %q to bucket %q: %w", key, b.bucket, err)
 	}
 	return nil
 }
+
+// ObjectSize returns the size in bytes of <bucket>/<key>, or 0 if there is
+// no such object.
+func (b *BucketUploader) ObjectSize(ctx context.Context, key string) (int64, error) {
+	out, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat %q in bucket %q: %w", key, b.bucket, err)
+	}
+	return aws.ToInt64(out.ContentLength), nil
+}
+
+func contentType(key string) string {
+	switch {
+	case strings.HasSuffix(key, ".csv"):
+		return "text/csv"
+	case strings.HasSuffix(key, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(key, ".json"):
+		return "application/json"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,118 @@
+package symbols
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Kind is the instrument type NSE expects in the `type` query parameter of
+// its option chain endpoints.
+type Kind string
+
+const (
+	Indices  Kind = "Indices"
+	Equities Kind = "Equity"
+)
+
+// DefaultSpec is used when NSE_SYMBOLS is unset, matching the original
+// NIFTY-only deployment.
+const DefaultSpec = "NIFTY:Indices"
+
+// Symbol is one underlying whose option chain is fetched and processed.
+type Symbol struct {
+	Name string
+	Kind Kind
+	// PollInterval overrides the fetcher's default market-hours poll
+	// interval for this symbol. Zero means use the default.
+	PollInterval time.Duration
+}
+
+// Slug is the lowercase identifier used for the symbol's Redis stream and
+// bucket prefix. NIFTY keeps its historical "nifty50" name so existing
+// streams and uploaded CSVs stay where they were.
+func (s Symbol) Slug() string {
+	if s.Name == "NIFTY" {
+		return "nifty50"
+	}
+	return strings.ToLower(s.Name)
+}
+
+// StreamKey is the Redis stream the fetcher publishes this symbol's
+// snapshots to and the processor reads them from.
+func (s Symbol) StreamKey() string {
+	return s.Slug() + ":option_chain"
+}
+
+// Parse reads a comma-separated list of NAME[:TYPE[:INTERVAL]] entries,
+// e.g. "NIFTY:Indices,BANKNIFTY:Indices:1m,RELIANCE:Equity:5m". TYPE
+// defaults to Indices.
+func Parse(spec string) ([]Symbol, error) {
+	var out []Symbol
+	seen := make(map[string]struct{})
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid symbol entry %q", entry)
+		}
+
+		sym := Symbol{Name: strings.ToUpper(strings.TrimSpace(parts[0])), Kind: Indices}
+		if sym.Name == "" {
+			return nil, fmt.Errorf("missing symbol name in %q", entry)
+		}
+
+		if len(parts) >= 2 {
+			kind, err := parseKind(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("symbol %s: %w", sym.Name, err)
+			}
+			sym.Kind = kind
+		}
+
+		if len(parts) == 3 {
+			interval, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("symbol %s: invalid poll interval %q", sym.Name, parts[2])
+			}
+			sym.PollInterval = interval
+		}
+
+		if _, dup := seen[sym.Name]; dup {
+			return nil, fmt.Errorf("symbol %s listed more than once", sym.Name)
+		}
+		seen[sym.Name] = struct{}{}
+		out = append(out, sym)
+	}
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no symbols configured")
+	}
+	return out, nil
+}
+
+// FromEnv parses NSE_SYMBOLS, falling back to DefaultSpec.
+func FromEnv() ([]Symbol, error) {
+	spec := os.Getenv("NSE_SYMBOLS")
+	if spec == "" {
+		spec = DefaultSpec
+	}
+	return Parse(spec)
+}
+
+func parseKind(s string) (Kind, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "indices", "index":
+		return Indices, nil
+	case "equity", "equities", "stock", "stocks":
+		return Equities, nil
+	default:
+		return "", fmt.Errorf("unknown instrument type %q", s)
+	}
+}
@@ -0,0 +1,73 @@
+package symbols
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    []Symbol
+		wantErr bool
+	}{
+		{
+			name: "default",
+			env:  "",
+			want: []Symbol{{Name: "NIFTY", Kind: Indices}},
+		},
+		{
+			name: "indices and equities",
+			env:  "nifty, BANKNIFTY:Indices:1m ,RELIANCE:Equity:5m,M&M:stocks",
+			want: []Symbol{
+				{Name: "NIFTY", Kind: Indices},
+				{Name: "BANKNIFTY", Kind: Indices, PollInterval: time.Minute},
+				{Name: "RELIANCE", Kind: Equities, PollInterval: 5 * time.Minute},
+				{Name: "M&M", Kind: Equities},
+			},
+		},
+		{name: "bad type", env: "NIFTY:Futures", wantErr: true},
+		{name: "bad interval", env: "NIFTY:Indices:soon", wantErr: true},
+		{name: "duplicate", env: "NIFTY,nifty", wantErr: true},
+		{name: "no names", env: " , ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NSE_SYMBOLS", tt.env)
+			got, err := FromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("FromEnv() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromEnv: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FromEnv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugAndStreamKey(t *testing.T) {
+	tests := []struct {
+		sym             Symbol
+		slug, streamKey string
+	}{
+		{Symbol{Name: "NIFTY", Kind: Indices}, "nifty50", "nifty50:option_chain"},
+		{Symbol{Name: "RELIANCE", Kind: Equities}, "reliance", "reliance:option_chain"},
+	}
+	for _, tt := range tests {
+		if got := tt.sym.Slug(); got != tt.slug {
+			t.Errorf("%s: Slug() = %q, want %q", tt.sym.Name, got, tt.slug)
+		}
+		if got := tt.sym.StreamKey(); got != tt.streamKey {
+			t.Errorf("%s: StreamKey() = %q, want %q", tt.sym.Name, got, tt.streamKey)
+		}
+	}
+}